@@ -0,0 +1,177 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// AnimationOptions configures the frames of Animate. Zero values use the
+// defaults.
+type AnimationOptions struct {
+	Scale      int // Size of a module in pixels. Defaults to 8.
+	Delay      int // Delay after each frame in 100ths of a second. Defaults to 5.
+	StageDelay int // Delay after the last frame of each stage, three times as long for the last frame. Defaults to 80.
+	DataFrames int // Number of frames the data bits are placed in. Defaults to 40.
+	MaskFrames int // Number of frames the mask is applied in, row by row. Defaults to 10.
+	LoopCount  int // See gif.GIF. 0 loops forever, -1 shows the animation once.
+}
+
+// Palette indices of the animation.
+const (
+	animationLight = iota
+	animationDark
+	animationEmpty // Modules that have not been placed yet.
+)
+
+var animationPalette = color.Palette{
+	color.White,
+	color.Black,
+	color.RGBA{0xd0, 0xd0, 0xd0, 0xff},
+}
+
+// Animate writes an animated GIF of the QR Code being built: the finder
+// patterns, timing patterns, alignment patterns, version and format
+// information, the data bits in the order they are placed and finally the
+// mask being applied.
+func (qr *QRCode) Animate(w io.Writer, options *AnimationOptions) error {
+	opts := AnimationOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Scale < 1 {
+		opts.Scale = 8
+	}
+	if opts.Delay < 1 {
+		opts.Delay = 5
+	}
+	if opts.StageDelay < 1 {
+		opts.StageDelay = 80
+	}
+	if opts.DataFrames < 1 {
+		opts.DataFrames = 40
+	}
+	if opts.MaskFrames < 1 {
+		opts.MaskFrames = 10
+	}
+
+	roles := qr.Roles()
+	size := len(roles)
+	state := make([]uint8, size*size)
+	for y := range roles {
+		for x := range roles[y] {
+			if roles[y][x].Role != RoleQuietZone {
+				state[y*size+x] = animationEmpty
+			}
+		}
+	}
+
+	animation := &gif.GIF{LoopCount: opts.LoopCount}
+	// Frames only cover the modules that changed, on top of the previous frame.
+	addFrame := func(modules []image.Point, delay int) {
+		if len(modules) == 0 {
+			return
+		}
+		bounds := image.Rectangle{Min: modules[0], Max: modules[0].Add(image.Point{1, 1})}
+		for _, p := range modules {
+			bounds = bounds.Union(image.Rectangle{Min: p, Max: p.Add(image.Point{1, 1})})
+		}
+
+		img := image.NewPaletted(image.Rect(
+			bounds.Min.X*opts.Scale, bounds.Min.Y*opts.Scale,
+			bounds.Max.X*opts.Scale, bounds.Max.Y*opts.Scale,
+		), animationPalette)
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+			for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+				img.SetColorIndex(x, y, state[(y/opts.Scale)*size+x/opts.Scale])
+			}
+		}
+
+		animation.Image = append(animation.Image, img)
+		animation.Delay = append(animation.Delay, delay)
+		animation.Disposal = append(animation.Disposal, gif.DisposalNone)
+	}
+	set := func(p image.Point, dark bool) {
+		state[p.Y*size+p.X] = animationLight
+		if dark {
+			state[p.Y*size+p.X] = animationDark
+		}
+	}
+
+	all := make([]image.Point, 0, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			all = append(all, image.Point{x, y})
+		}
+	}
+	addFrame(all, opts.StageDelay)
+
+	// Function patterns, with their final modules.
+	stages := [][]Role{
+		{RoleFinder, RoleSeparator},
+		{RoleTiming},
+		{RoleAlignment},
+		{RoleVersion, RoleFormat, RoleDarkModule},
+	}
+	for _, stage := range stages {
+		var modules []image.Point
+		for _, p := range all {
+			for _, role := range stage {
+				if roles[p.Y][p.X].Role == role {
+					modules = append(modules, p)
+					set(p, qr.qr.At(p.X, p.Y))
+				}
+			}
+		}
+		addFrame(modules, opts.StageDelay)
+	}
+
+	// Data bits in placement order, before masking.
+	mask := maskPattern(qr.pattern)
+	data := NewBitmap(qr.size, qr.size)
+	for _, p := range all {
+		switch roles[p.Y][p.X].Role {
+		case RoleData, RoleErrorCorrection, RoleRemainder:
+			data.Set(p.X-QuietZone, p.Y-QuietZone, true)
+		}
+	}
+	var order []image.Point
+	walkData(qr.size, data, func(x, y int) {
+		order = append(order, image.Point{x + QuietZone, y + QuietZone})
+	})
+	chunk := (len(order) + opts.DataFrames - 1) / opts.DataFrames
+	for i := 0; i < len(order); i += chunk {
+		modules := order[i:min(i+chunk, len(order))]
+		for _, p := range modules {
+			set(p, qr.qr.At(p.X, p.Y) != mask(p.X-QuietZone, p.Y-QuietZone))
+		}
+		delay := opts.Delay
+		if i+chunk >= len(order) {
+			delay = opts.StageDelay
+		}
+		addFrame(modules, delay)
+	}
+
+	// The mask, applied from top to bottom.
+	rows := (qr.size + opts.MaskFrames - 1) / opts.MaskFrames
+	for row := 0; row < qr.size; row += rows {
+		var modules []image.Point
+		for y := row; y < min(row+rows, qr.size); y++ {
+			for x := 0; x < qr.size; x++ {
+				if data.At(x, y) {
+					p := image.Point{x + QuietZone, y + QuietZone}
+					modules = append(modules, p)
+					set(p, qr.qr.At(p.X, p.Y))
+				}
+			}
+		}
+		delay := opts.Delay
+		if row+rows >= qr.size {
+			delay = opts.StageDelay * 3
+		}
+		addFrame(modules, delay)
+	}
+
+	return gif.EncodeAll(w, animation)
+}
@@ -0,0 +1,116 @@
+package qr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Base45Encode encodes data as Base45. The Base45 alphabet is the Alphanumeric
+// mode character set, so the result is always encoded in Alphanumeric mode.
+// See: https://www.rfc-editor.org/rfc/rfc9285
+func Base45Encode(data []byte) string {
+	var builder strings.Builder
+	builder.Grow((len(data)*3 + 1) / 2)
+
+	for i := 0; i < len(data); i += 2 {
+		if i+1 < len(data) {
+			n := int(data[i])*256 + int(data[i+1])
+			builder.WriteByte(alphanumChars[n%45])
+			builder.WriteByte(alphanumChars[n/45%45])
+			builder.WriteByte(alphanumChars[n/2025])
+		} else {
+			n := int(data[i])
+			builder.WriteByte(alphanumChars[n%45])
+			builder.WriteByte(alphanumChars[n/45])
+		}
+	}
+
+	return builder.String()
+}
+
+// Base45Decode decodes Base45 encoded data.
+func Base45Decode(data string) ([]byte, error) {
+	if len(data)%3 == 1 {
+		return nil, fmt.Errorf("invalid base45 length: %d", len(data))
+	}
+
+	decoded := make([]byte, 0, len(data)/3*2+1)
+	for i := 0; i < len(data); i += 3 {
+		n, weight := 0, 1
+		for j := i; j < len(data) && j < i+3; j++ {
+			value := strings.IndexByte(alphanumChars, data[j])
+			if value < 0 {
+				return nil, fmt.Errorf("invalid base45 character %q at offset %d", data[j], j)
+			}
+			n += value * weight
+			weight *= 45
+		}
+		if i+2 < len(data) {
+			if n > 0xffff {
+				return nil, fmt.Errorf("invalid base45 triplet at offset %d", i)
+			}
+			decoded = append(decoded, byte(n>>8), byte(n))
+		} else {
+			if n > 0xff {
+				return nil, fmt.Errorf("invalid base45 pair at offset %d", i)
+			}
+			decoded = append(decoded, byte(n))
+		}
+	}
+
+	return decoded, nil
+}
+
+// CompressPayload compresses data with zlib and encodes the result as Base45,
+// the scheme used for health certificates.
+func CompressPayload(data []byte) (string, error) {
+	var compressed bytes.Buffer
+	writer, err := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return Base45Encode(compressed.Bytes()), nil
+}
+
+// DecompressPayload reverses CompressPayload.
+func DecompressPayload(data string) ([]byte, error) {
+	compressed, err := Base45Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// NewCompressedQRCode builds a QR Code from the zlib compressed, Base45 encoded
+// data in Alphanumeric mode. Use DecompressPayload on the scanned text to
+// recover the data.
+func NewCompressedQRCode(data []byte, options *Options) (*QRCode, error) {
+	payload, err := CompressPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := copyOptions(options)
+	if opts.Mode == Numeric {
+		return nil, fmt.Errorf("%w: compressed payloads cannot be encoded in Numeric mode", ErrInvalidMode)
+	}
+	if opts.Mode == 0 {
+		opts.Mode = AlphaNum
+	}
+
+	return NewQRCode(payload, &opts)
+}
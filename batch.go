@@ -0,0 +1,114 @@
+package qr
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Item is the input of a single QR Code in GenerateBatch.
+type Item struct {
+	Data    string
+	Options *Options
+}
+
+// Result is the output of GenerateBatch for the Item at Index in the input.
+type Result struct {
+	Index  int
+	QRCode *QRCode
+	Err    error
+}
+
+// GenerateBatch builds QR Codes for all items received from inputs using the
+// given number of workers, each with its own Encoder. If workers is less than
+// 1, GOMAXPROCS workers are used. Results are sent in input order. The
+// returned channel is closed once inputs is closed and all results have been
+// sent, or when ctx is cancelled.
+func GenerateBatch(ctx context.Context, inputs <-chan Item, workers int) <-chan Result {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct {
+		index int
+		item  Item
+	}
+
+	jobs := make(chan job)
+	done := make(chan Result)
+	results := make(chan Result)
+	// Limits the number of items in flight, so a slow item does not cause
+	// unbounded buffering of the results after it.
+	tokens := make(chan struct{}, workers*2)
+
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case item, ok := <-inputs:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- job{index, item}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			encoder := NewEncoder()
+			for job := range jobs {
+				qr, err := encoder.Encode(job.item.Data, job.item.Options)
+				select {
+				case done <- Result{Index: job.index, QRCode: qr, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Reorder the results.
+	go func() {
+		defer close(results)
+		pending := map[int]Result{}
+		next := 0
+		for result := range done {
+			pending[result.Index] = result
+			for {
+				result, ok := pending[next]
+				if !ok {
+					break
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+				delete(pending, next)
+				<-tokens
+				next++
+			}
+		}
+	}()
+
+	return results
+}
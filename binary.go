@@ -0,0 +1,64 @@
+package qr
+
+import (
+	"fmt"
+	"io"
+)
+
+// NewQRCodeBytes builds a QR Code for binary data such as protobuf, CBOR or
+// compressed payloads. The data is always encoded in Byte mode, even if it
+// only contains digits or Alphanumeric characters.
+func NewQRCodeBytes(data []byte, options *Options) (*QRCode, error) {
+	opts, err := byteOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return NewQRCode(string(data), opts)
+}
+
+// NewQRCodeReader builds a QR Code in Byte mode for the data read from r.
+// Reading stops with a *DataTooLargeError as soon as more bytes are read than
+// fit in the requested version, or in the largest allowed version if no
+// version is given.
+func NewQRCodeReader(r io.Reader, options *Options) (*QRCode, error) {
+	opts, err := byteOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	level := opts.Error
+	if level == "" {
+		level = "L"
+	}
+	version := opts.Version
+	if version == 0 {
+		version = 40
+		if opts.MaxVersion != 0 {
+			version = opts.MaxVersion
+		}
+	}
+	limit, err := MaxChars(version, level, Byte)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		qr := &QRCode{mode: Byte, errorLevel: level}
+		return nil, qr.tooLarge(version, []Segment{{Mode: Byte, Data: string(data)}})
+	}
+
+	return NewQRCode(string(data), opts)
+}
+
+func byteOptions(options *Options) (*Options, error) {
+	opts := copyOptions(options)
+	if opts.Mode != 0 && opts.Mode != Byte {
+		return nil, fmt.Errorf("%w: binary data must be encoded in Byte mode", ErrInvalidMode)
+	}
+	opts.Mode = Byte
+	return &opts, nil
+}
@@ -1,5 +1,13 @@
 package qr
 
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"strings"
+)
+
 type Bitmap struct {
 	width  int
 	height int
@@ -21,6 +29,33 @@ func NewBitmap(width, height int) *Bitmap {
 	}
 }
 
+// Resizes the bitmap and clears all bits, reusing its storage if possible.
+func (b *Bitmap) reset(width, height int) {
+	total := width * height
+	size := (total + 63) / 64
+	if cap(b.data) < size {
+		b.data = make([]uint64, size)
+	} else {
+		b.data = b.data[:size]
+		for i := range b.data {
+			b.data[i] = 0
+		}
+	}
+	b.width = width
+	b.height = height
+}
+
+// Copies the contents of other into b, resizing b if necessary.
+func (b *Bitmap) copyFrom(other *Bitmap) {
+	if cap(b.data) < len(other.data) {
+		b.data = make([]uint64, len(other.data))
+	}
+	b.data = b.data[:len(other.data)]
+	copy(b.data, other.data)
+	b.width = other.width
+	b.height = other.height
+}
+
 func (b *Bitmap) Width() int {
 	return b.width
 }
@@ -35,12 +70,26 @@ func (b *Bitmap) Copy() *Bitmap {
 	return new
 }
 
+// In reports whether (x, y) lies inside the bitmap.
+func (b *Bitmap) In(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.width && y < b.height
+}
+
+// At reports whether the module at (x, y) is set. Modules outside of the
+// bitmap are never set.
 func (b *Bitmap) At(x, y int) bool {
+	if !b.In(x, y) {
+		return false
+	}
 	index := y*b.width + x
 	return (b.data[index/64] & (1 << (index & 63))) != 0
 }
 
+// Set sets the module at (x, y). Modules outside of the bitmap are ignored.
 func (b *Bitmap) Set(x, y int, value bool) {
+	if !b.In(x, y) {
+		return
+	}
 	index := y*b.width + x
 	if value {
 		b.data[index/64] |= (1 << (index & 63))
@@ -69,4 +118,278 @@ func (b *Bitmap) Invert() {
 	for i := 0; i < len(b.data); i++ {
 		b.data[i] = ^b.data[i]
 	}
+	b.clearTail()
+}
+
+// Clears the unused bits of the last word.
+func (b *Bitmap) clearTail() {
+	if rest := b.width * b.height % 64; rest != 0 {
+		b.data[len(b.data)-1] &= 1<<rest - 1
+	}
+}
+
+// Equal reports whether both bitmaps have the same size and modules.
+func (b *Bitmap) Equal(other *Bitmap) bool {
+	if b.width != other.width || b.height != other.height {
+		return false
+	}
+	for i := range b.data {
+		if b.data[i] != other.data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bitmap) checkSize(other *Bitmap) {
+	if b.width != other.width || b.height != other.height {
+		panic(fmt.Sprintf("bitmap size mismatch: %dx%d and %dx%d", b.width, b.height, other.width, other.height))
+	}
+}
+
+// Xor sets b to b XOR other. Panics if the sizes differ.
+func (b *Bitmap) Xor(other *Bitmap) {
+	b.checkSize(other)
+	b.xor(other)
+}
+
+// And sets b to b AND other. Panics if the sizes differ.
+func (b *Bitmap) And(other *Bitmap) {
+	b.checkSize(other)
+	for i := range b.data {
+		b.data[i] &= other.data[i]
+	}
+}
+
+// Or sets b to b OR other. Panics if the sizes differ.
+func (b *Bitmap) Or(other *Bitmap) {
+	b.checkSize(other)
+	for i := range b.data {
+		b.data[i] |= other.data[i]
+	}
+}
+
+// PopCount returns the number of set modules.
+func (b *Bitmap) PopCount() int {
+	return b.popCount()
+}
+
+// Row returns the modules of row y.
+func (b *Bitmap) Row(y int) []bool {
+	row := make([]bool, b.width)
+	for x := range row {
+		row[x] = b.At(x, y)
+	}
+	return row
+}
+
+// Rotate90 returns the bitmap rotated by 90 degrees clockwise.
+func (b *Bitmap) Rotate90() *Bitmap {
+	// Rotating clockwise is transposing and mirroring horizontally.
+	transposed := NewBitmap(0, 0)
+	b.transpose(transposed)
+	return transposed.FlipH()
+}
+
+// FlipH returns the bitmap mirrored horizontally.
+func (b *Bitmap) FlipH() *Bitmap {
+	flipped := NewBitmap(b.width, b.height)
+	n := (b.width + 63) / 64
+	row := make([]uint64, n)
+	reversed := make([]uint64, n)
+	// Reversing the words mirrors the row within 64*n bits, the shift moves
+	// it back to the start.
+	shift := uint(64*n - b.width)
+	for y := 0; y < b.height; y++ {
+		b.rowBits(y, row)
+		for i := range reversed {
+			reversed[i] = bits.Reverse64(row[n-1-i]) >> shift
+			if shift != 0 && i+1 < n {
+				reversed[i] |= bits.Reverse64(row[n-2-i]) << (64 - shift)
+			}
+		}
+		flipped.setRowBits(y, reversed)
+	}
+	return flipped
+}
+
+// FlipV returns the bitmap mirrored vertically.
+func (b *Bitmap) FlipV() *Bitmap {
+	flipped := NewBitmap(b.width, b.height)
+	row := make([]uint64, (b.width+63)/64)
+	for y := 0; y < b.height; y++ {
+		b.rowBits(y, row)
+		flipped.setRowBits(b.height-1-y, row)
+	}
+	return flipped
+}
+
+// Crop returns the w x h region with its top left corner at (x, y). The
+// region may extend beyond the bitmap, in which case those modules are unset.
+// A negative width or height is treated as 0.
+func (b *Bitmap) Crop(x, y, w, h int) *Bitmap {
+	cropped := NewBitmap(max(w, 0), max(h, 0))
+	cropped.Place(-x, -y, b)
+	return cropped
+}
+
+// Scale returns the bitmap with each module scaled to n x n modules.
+func (b *Bitmap) Scale(n int) *Bitmap {
+	if n < 1 {
+		n = 1
+	}
+	scaled := NewBitmap(b.width*n, b.height*n)
+	row := make([]uint64, (scaled.width+63)/64)
+	for y := 0; y < b.height; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := 0; x < b.width; x++ {
+			if b.At(x, y) {
+				for i := x * n; i < (x+1)*n; i++ {
+					row[i/64] |= 1 << (i % 64)
+				}
+			}
+		}
+		for i := y * n; i < (y+1)*n; i++ {
+			scaled.setRowBits(i, row)
+		}
+	}
+	return scaled
+}
+
+// String returns the bitmap as text with "#" for set and "." for unset
+// modules, one line per row.
+func (b *Bitmap) String() string {
+	var builder strings.Builder
+	builder.Grow((b.width + 1) * b.height)
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if b.At(x, y) {
+				builder.WriteByte('#')
+			} else {
+				builder.WriteByte('.')
+			}
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// Gray returns the bitmap as an image with one black pixel per set module
+// and one white pixel per unset module.
+func (b *Bitmap) Gray() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, b.width, b.height))
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if !b.At(x, y) {
+				img.Pix[y*img.Stride+x] = 0xff
+			}
+		}
+	}
+	return img
+}
+
+// Paletted returns the bitmap as an image with a black and white palette.
+// Set modules have palette index 1.
+func (b *Bitmap) Paletted() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, b.width, b.height), color.Palette{color.White, color.Black})
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if b.At(x, y) {
+				img.Pix[y*img.Stride+x] = 1
+			}
+		}
+	}
+	return img
+}
+
+// NewBitmapFromImage returns a bitmap with one module per pixel of img.
+// Pixels darker than 50% gray are set.
+func NewBitmapFromImage(img image.Image) *Bitmap {
+	bounds := img.Bounds()
+	bitmap := NewBitmap(bounds.Dx(), bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.Gray16Model.Convert(img.At(x, y)).(color.Gray16)
+			bitmap.Set(x-bounds.Min.X, y-bounds.Min.Y, gray.Y < 0x8000)
+		}
+	}
+	return bitmap
+}
+
+// Sets b to b XOR other. Both bitmaps must have the same size.
+func (b *Bitmap) xor(other *Bitmap) {
+	for i := range b.data {
+		b.data[i] ^= other.data[i]
+	}
+}
+
+// Writes the transpose of b into dst.
+func (b *Bitmap) transpose(dst *Bitmap) {
+	dst.reset(b.height, b.width)
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if b.At(x, y) {
+				dst.Set(y, x, true)
+			}
+		}
+	}
+}
+
+// Copies row y into dst, least significant bit first. Bits beyond the width of
+// the bitmap are cleared.
+func (b *Bitmap) rowBits(y int, dst []uint64) {
+	start := y * b.width
+	for i := range dst {
+		dst[i] = 0
+		offset := i * 64
+		if offset >= b.width {
+			continue
+		}
+		index := start + offset
+		word, shift := index/64, uint(index%64)
+		dst[i] = b.data[word] >> shift
+		if shift != 0 && word+1 < len(b.data) {
+			dst[i] |= b.data[word+1] << (64 - shift)
+		}
+		if b.width-offset < 64 {
+			dst[i] &= 1<<(b.width-offset) - 1
+		}
+	}
+}
+
+// Returns the number of set bits.
+func (b *Bitmap) popCount() int {
+	count := 0
+	total := b.width * b.height
+	for i, word := range b.data {
+		// Ignore the unused bits of the last word.
+		if rest := total - i*64; rest < 64 {
+			word &= 1<<rest - 1
+		}
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Sets row y to the first width bits of src, least significant bit first.
+func (b *Bitmap) setRowBits(y int, src []uint64) {
+	start := y * b.width
+	for offset := 0; offset < b.width; offset += 64 {
+		n := min(64, b.width-offset)
+		bits := src[offset/64]
+		index := start + offset
+		word, shift := index/64, uint(index%64)
+		// Write the n bits at index, possibly spanning two words.
+		mask := ^uint64(0)
+		if n < 64 {
+			mask = 1<<n - 1
+		}
+		bits &= mask
+		b.data[word] = b.data[word]&^(mask<<shift) | bits<<shift
+		if shift != 0 && uint(n) > 64-shift {
+			b.data[word+1] = b.data[word+1]&^(mask>>(64-shift)) | bits>>(64-shift)
+		}
+	}
 }
@@ -1,64 +1,98 @@
 package qr
 
 import (
-	"bytes"
-	"fmt"
-	"strconv"
+	"strings"
 )
 
+// Buffer is a sequence of bits stored most significant bit first.
 type Buffer struct {
-	size   int
-	buffer bytes.Buffer
+	size int
+	data []byte
 }
 
 func NewBuffer() *Buffer {
-	return &Buffer{
-		size:   0,
-		buffer: bytes.Buffer{},
-	}
+	return &Buffer{}
 }
 
-func (b *Buffer) Size() int {
+// Len returns the number of bits in the buffer.
+func (b *Buffer) Len() int {
 	return b.size
 }
 
-func (b *Buffer) String() string {
-	return b.buffer.String()
+// At returns the bit at index i.
+func (b *Buffer) At(i int) bool {
+	return b.data[i/8]&(0x80>>(i%8)) != 0
 }
 
-func (b *Buffer) Bytes() []byte {
-	size := b.size / 8
-	if b.size%8 != 0 {
-		size++
+// String returns the bits as a string of '0' and '1' characters.
+func (b *Buffer) String() string {
+	var builder strings.Builder
+	builder.Grow(b.size)
+	for i := 0; i < b.size; i++ {
+		if b.At(i) {
+			builder.WriteByte('1')
+		} else {
+			builder.WriteByte('0')
+		}
 	}
+	return builder.String()
+}
 
-	data := make([]byte, size)
+// Bytes returns a copy of the bits packed into bytes. If the number of bits is
+// not a multiple of 8, the last byte is padded with zeros, so its bits are
+// left-aligned: "101" is returned as 0b10100000. Before Buffer was bit-packed,
+// the last byte was right-aligned instead.
+func (b *Buffer) Bytes() []byte {
+	data := make([]byte, len(b.data))
+	copy(data, b.data)
+	return data
+}
 
-	i := 0
-	s := b.String()
-	for ; i < len(s)-8; i += 8 {
-		bits, _ := strconv.ParseUint(s[i:i+8], 2, 64)
-		data[i/8] = byte(bits)
+// AppendBits appends the size least significant bits of val.
+func (b *Buffer) AppendBits(val, size int) {
+	for size > 0 {
+		if b.size%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		free := 8 - b.size%8
+		n := min(free, size)
+		bits := (val >> (size - n)) & (1<<n - 1)
+		b.data[len(b.data)-1] |= byte(bits << (free - n))
+		b.size += n
+		size -= n
 	}
-	bits, _ := strconv.ParseUint(s[i:], 2, 64)
-	data[i/8] = byte(bits)
+}
 
-	return data
+// Reset removes all bits from the buffer but keeps its storage.
+func (b *Buffer) Reset() {
+	b.data = b.data[:0]
+	b.size = 0
 }
 
+// Deprecated: Use Len.
+func (b *Buffer) Size() int {
+	return b.Len()
+}
+
+// Deprecated: Use AppendBits.
 func (b *Buffer) Add(val, size int) {
-	if size > 0 {
-		b.buffer.WriteString(fmt.Sprintf("%0*b", size, val))
-		b.size += size
-	}
+	b.AppendBits(val, size)
 }
 
+// Write appends the bits of a string of '0' and '1' characters.
+//
+// Deprecated: Use AppendBits.
 func (b *Buffer) Write(data string) {
-	b.buffer.WriteString(data)
-	b.size += len(data)
+	for i := 0; i < len(data); i++ {
+		if data[i] == '1' {
+			b.AppendBits(1, 1)
+		} else {
+			b.AppendBits(0, 1)
+		}
+	}
 }
 
+// Deprecated: Use Reset.
 func (b *Buffer) Clear() {
-	b.buffer.Reset()
-	b.size = 0
+	b.Reset()
 }
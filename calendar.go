@@ -0,0 +1,227 @@
+package qr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event describes a calendar event encoded as a minimal iCalendar VEVENT.
+// See: https://www.rfc-editor.org/rfc/rfc5545
+type Event struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	// Times in UTC are written with a "Z" suffix, times in a location that
+	// time.LoadLocation knows, such as "Europe/Berlin", are written with a
+	// TZID parameter and all other times are converted to UTC.
+	Start time.Time
+	End   time.Time // Optional.
+	// Stamp is the creation time of the event, written as DTSTAMP in UTC.
+	// Defaults to the current time.
+	Stamp time.Time
+}
+
+const (
+	icalTime     = "20060102T150405"
+	icalLineSize = 75 // Maximum line length in octets, excluding the line break.
+)
+
+// Payload validates the event and returns the VEVENT text.
+func (e *Event) Payload() (string, error) {
+	if e.Summary == "" {
+		return "", fmt.Errorf("event summary must not be empty")
+	}
+	if e.Start.IsZero() {
+		return "", fmt.Errorf("event start must be set")
+	}
+	if !e.End.IsZero() && e.End.Before(e.Start) {
+		return "", fmt.Errorf("event end is before event start")
+	}
+
+	var lines []string
+	lines = append(lines, "BEGIN:VEVENT")
+	if e.UID != "" {
+		lines = append(lines, "UID:"+icalEscape(e.UID))
+	}
+	stamp := e.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	lines = append(lines, "DTSTAMP:"+stamp.UTC().Format(icalTime)+"Z")
+	lines = append(lines, "SUMMARY:"+icalEscape(e.Summary))
+	lines = append(lines, icalFormatTime("DTSTART", e.Start))
+	if !e.End.IsZero() {
+		lines = append(lines, icalFormatTime("DTEND", e.End))
+	}
+	if e.Location != "" {
+		lines = append(lines, "LOCATION:"+icalEscape(e.Location))
+	}
+	if e.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+icalEscape(e.Description))
+	}
+	lines = append(lines, "END:VEVENT")
+
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(icalFold(line))
+		builder.WriteString("\r\n")
+	}
+
+	return builder.String(), nil
+}
+
+func icalFormatTime(name string, t time.Time) string {
+	location := t.Location().String()
+	if location == "UTC" || location == "" || location == "Local" {
+		return name + ":" + t.UTC().Format(icalTime) + "Z"
+	}
+	// Fixed zones such as time.FixedZone("+0100", 3600) have no TZID that a
+	// reader can resolve without a VTIMEZONE.
+	if _, err := time.LoadLocation(location); err != nil {
+		return name + ":" + t.UTC().Format(icalTime) + "Z"
+	}
+	return name + ";TZID=" + location + ":" + t.Format(icalTime)
+}
+
+func icalEscape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icalUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// Splits a content line into lines of at most 75 octets. Continuation lines
+// start with a single space. Multi-byte characters are never split.
+func icalFold(line string) string {
+	var builder strings.Builder
+	limit := icalLineSize
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		builder.WriteString(line[:cut])
+		builder.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineSize - 1 // Account for the leading space.
+	}
+	builder.WriteString(line)
+	return builder.String()
+}
+
+// ParseEvent parses a VEVENT as produced by Event.Payload. Properties outside
+// of the VEVENT and inside nested components such as VALARM are ignored.
+func ParseEvent(data string) (*Event, error) {
+	// Unfold continuation lines.
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	event := &Event{}
+	inside := false
+	nested := 0 // Depth of components inside the VEVENT.
+	for _, line := range strings.Split(data, "\n") {
+		if line == "" {
+			continue
+		}
+		name, params, value, err := icalSplit(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case name == "BEGIN" && !inside:
+			inside = value == "VEVENT"
+			continue
+		case name == "BEGIN":
+			nested++
+			continue
+		case name == "END" && nested > 0:
+			nested--
+			continue
+		case name == "END" && inside && value == "VEVENT":
+			if event.Start.IsZero() {
+				return nil, fmt.Errorf("event is missing DTSTART")
+			}
+			return event, nil
+		case !inside || nested > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = icalUnescape(value)
+		case "SUMMARY":
+			event.Summary = icalUnescape(value)
+		case "LOCATION":
+			event.Location = icalUnescape(value)
+		case "DESCRIPTION":
+			event.Description = icalUnescape(value)
+		case "DTSTART", "DTEND", "DTSTAMP":
+			t, err := icalParseTime(params["TZID"], value)
+			if err != nil {
+				return nil, err
+			}
+			switch name {
+			case "DTSTART":
+				event.Start = t
+			case "DTEND":
+				event.End = t
+			case "DTSTAMP":
+				event.Stamp = t
+			}
+		}
+	}
+
+	if !inside {
+		return nil, fmt.Errorf("data does not contain a VEVENT")
+	}
+	return nil, fmt.Errorf("event is missing END:VEVENT")
+}
+
+// Splits "NAME;PARAM=VALUE:VALUE" into its components.
+func icalSplit(line string) (string, map[string]string, string, error) {
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("invalid iCalendar line: %s", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		if i := strings.Index(param, "="); i >= 0 {
+			params[strings.ToUpper(param[:i])] = strings.Trim(param[i+1:], `"`)
+		}
+	}
+
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+func icalParseTime(tzid, value string) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalTime, strings.TrimSuffix(value, "Z"))
+	}
+	location := time.Local
+	if tzid != "" {
+		var err error
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.ParseInLocation(icalTime, value, location)
+}
@@ -0,0 +1,114 @@
+package qr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chars holds a number of characters for each mode.
+type Chars struct {
+	Numeric  int
+	AlphaNum int
+	Byte     int
+}
+
+func checkLevel(level string) (int, error) {
+	if len(level) != 1 || !strings.Contains("LMQH", level) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidLevel, level)
+	}
+	return strings.Index("LMQH", level), nil
+}
+
+// MaxChars returns the maximum number of characters of the given mode that fit
+// in a QR Code of the given version and error level.
+func MaxChars(version int, level string, mode int) (int, error) {
+	errorIndex, err := checkLevel(level)
+	if err != nil {
+		return 0, err
+	}
+	if version < 1 || version > 40 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidVersion, version)
+	}
+
+	bits := capacity[(version-1)*4+errorIndex] - 4 - length(version, mode)
+
+	var chars int
+	switch mode {
+	case Numeric:
+		chars = bits / 10 * 3
+		if bits%10 >= 7 {
+			chars += 2
+		} else if bits%10 >= 4 {
+			chars++
+		}
+	case AlphaNum:
+		chars = bits / 11 * 2
+		if bits%11 >= 6 {
+			chars++
+		}
+	case Byte:
+		chars = bits / 8
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrInvalidMode, mode)
+	}
+
+	// The character count indicator limits the number of characters.
+	return min(chars, 1<<length(version, mode)-1), nil
+}
+
+// MinVersion returns the smallest version that fits the data at the given
+// error level in its best fitting mode, and the number of bits that remain
+// unused in that version.
+func MinVersion(data string, level string) (int, int, error) {
+	errorIndex, err := checkLevel(level)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	qr := &QRCode{mode: findMode(data), errorLevel: level}
+	segments := []Segment{{Mode: qr.mode, Data: data}}
+	version := qr.findOptimalVersion(segments)
+	if version > 40 {
+		return 0, 0, qr.tooLarge(40, segments)
+	}
+
+	used := qr.requiredBits(version, segments)
+	return version, capacity[(version-1)*4+errorIndex] - used, nil
+}
+
+// FitsIn reports whether the data fits in a QR Code of the given version and
+// error level.
+func FitsIn(data string, version int, level string) bool {
+	minimum, _, err := MinVersion(data, level)
+	return err == nil && version >= minimum && version <= 40
+}
+
+// Remaining returns how many more characters of each mode can be appended to
+// the data in a QR Code of the given version and error level. Appending
+// characters of a mode the data is not encoded in, for example letters to
+// digits, changes the mode of all of the data.
+func Remaining(data string, version int, level string) (Chars, error) {
+	// Empty data fits every mode, while findMode reports Byte for it.
+	mode := Numeric
+	if data != "" {
+		mode = findMode(data)
+	}
+	remaining := func(m int) (int, error) {
+		chars, err := MaxChars(version, level, max(m, mode))
+		return max(chars-len(data), 0), err
+	}
+
+	var chars Chars
+	var err error
+	if chars.Numeric, err = remaining(Numeric); err != nil {
+		return Chars{}, err
+	}
+	if chars.AlphaNum, err = remaining(AlphaNum); err != nil {
+		return Chars{}, err
+	}
+	if chars.Byte, err = remaining(Byte); err != nil {
+		return Chars{}, err
+	}
+
+	return chars, nil
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/AlexEidt/qr"
+)
+
+// A row of the batch input, keyed by column name.
+type row struct {
+	index  int // Starts at 1 for the first row after the CSV header.
+	values map[string]string
+	err    error // The row could not be read.
+}
+
+type batchResult struct {
+	row    int
+	name   string
+	output []byte
+	err    error
+}
+
+// Templates evaluated over the columns of each row.
+type batchTemplates struct {
+	data, name                                       *template.Template
+	version, mode, level, mask, scale, quiet, fg, bg *template.Template
+}
+
+func runBatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qr batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: qr batch [flags] -data template -o dir|file.zip [file]\n\n")
+		fmt.Fprintf(stderr, "Builds a QR Code for every row of a CSV or JSON Lines file, or standard input.\n")
+		fmt.Fprintf(stderr, "The data, name and option flags are text/template templates over the columns\n")
+		fmt.Fprintf(stderr, "of each row, for example -data \"https://example.com/assets/{{.id}}\".\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+
+	inputFormat := flags.String("input", "", "input format: csv or jsonl (default from the file extension, csv for standard input)")
+	output := flags.String("o", "", "write the QR Codes to `dir`, or to a zip archive if it ends in .zip")
+	report := flags.String("report", "", "write the failed rows as CSV to `file` instead of standard error")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "number of QR Codes built concurrently")
+	format := flags.String("format", "png", "output format: png, jpg, jpeg, svg or txt")
+	boost := flags.Bool("boost", false, "raise the error correction level as far as the version allows")
+	verify := flags.Bool("verify", false, "read every QR Code back before writing it")
+
+	sources := map[string]*string{
+		"data":    flags.String("data", "", "`template` of the data (required)"),
+		"name":    flags.String("name", "", "`template` of the file name without extension (default row number)"),
+		"version": flags.String("version", "0", "`template` of the version, 0 for the lowest version that fits"),
+		"mode":    flags.String("mode", "", "`template` of the mode: numeric, alphanumeric or byte"),
+		"level":   flags.String("level", "L", "`template` of the error correction level"),
+		"mask":    flags.String("mask", "-1", "`template` of the mask pattern, -1 for the lowest penalty"),
+		"scale":   flags.String("scale", "10", "`template` of the size of a module in pixels"),
+		"quiet":   flags.String("quiet", strconv.Itoa(qr.QuietZone), "`template` of the width of the quiet zone in modules"),
+		"fg":      flags.String("fg", "#000000", "`template` of the color of dark modules"),
+		"bg":      flags.String("bg", "#ffffff", "`template` of the color of light modules"),
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	usage := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, "qr batch: "+format+"\n", args...)
+		return 2
+	}
+	if *sources["data"] == "" {
+		return usage("-data is required")
+	}
+	if *output == "" {
+		return usage("-o is required")
+	}
+	if flags.NArg() > 1 {
+		return usage("at most one input file may be given")
+	}
+	switch *format {
+	case "png", "jpg", "jpeg", "svg", "txt":
+	default:
+		return usage("unsupported format: %q", *format)
+	}
+
+	templates := &batchTemplates{}
+	for name, field := range map[string]**template.Template{
+		"data": &templates.data, "name": &templates.name, "version": &templates.version,
+		"mode": &templates.mode, "level": &templates.level, "mask": &templates.mask,
+		"scale": &templates.scale, "quiet": &templates.quiet, "fg": &templates.fg, "bg": &templates.bg,
+	} {
+		t, err := template.New(name).Option("missingkey=error").Parse(*sources[name])
+		if err != nil {
+			return usage("invalid -%s template: %v", name, err)
+		}
+		*field = t
+	}
+
+	input := stdin
+	filename := flags.Arg(0)
+	if filename != "" && filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "qr batch: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	if *inputFormat == "" {
+		*inputFormat = "csv"
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".jsonl", ".ndjson":
+			*inputFormat = "jsonl"
+		}
+	}
+	var read func(io.Reader, chan<- row) error
+	switch *inputFormat {
+	case "csv":
+		read = readCSV
+	case "jsonl":
+		read = readJSONL
+	default:
+		return usage("unsupported input format: %q", *inputFormat)
+	}
+
+	write, closeOutput, err := openOutput(*output)
+	if err != nil {
+		fmt.Fprintf(stderr, "qr batch: %v\n", err)
+		return 1
+	}
+
+	// Read the rows, build the QR Codes concurrently and write them as they
+	// are done.
+	if *workers < 1 {
+		*workers = 1
+	}
+	incoming := make(chan row)
+	rows := make(chan row)
+	results := make(chan batchResult)
+	// Limits the number of rows in flight, so a slow row does not cause
+	// unbounded buffering of the results after it.
+	tokens := make(chan struct{}, *workers*2)
+	var readErr error
+	go func() {
+		defer close(incoming)
+		readErr = read(input, incoming)
+	}()
+	go func() {
+		defer close(rows)
+		for r := range incoming {
+			tokens <- struct{}{}
+			rows <- r
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rows {
+				results <- templates.build(r, *format, qr.Options{BoostError: *boost, Verify: *verify})
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var failures []batchResult
+	names := map[string]int{}
+	generated := 0
+	// Results are written in row order, so the output does not depend on
+	// which worker finishes first.
+	pending := map[int]batchResult{}
+	next := 1
+	for result := range results {
+		pending[result.row] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-tokens
+
+			if result.err == nil {
+				if row, ok := names[result.name]; ok {
+					result.err = fmt.Errorf("file name %q is already used by row %d", result.name, row)
+				} else {
+					names[result.name] = result.row
+					result.err = write(result.name, result.output)
+				}
+			}
+			if result.err != nil {
+				failures = append(failures, result)
+				continue
+			}
+			generated++
+		}
+	}
+
+	code := 0
+	if err := closeOutput(); err != nil {
+		fmt.Fprintf(stderr, "qr batch: %v\n", err)
+		code = 1
+	}
+	if readErr != nil {
+		fmt.Fprintf(stderr, "qr batch: reading input: %v\n", readErr)
+		code = 1
+	}
+
+	if err := writeReport(*report, failures, stderr); err != nil {
+		fmt.Fprintf(stderr, "qr batch: %v\n", err)
+		code = 1
+	}
+	fmt.Fprintf(stderr, "qr batch: %d QR Codes generated, %d failed\n", generated, len(failures))
+	if len(failures) > 0 {
+		code = 1
+	}
+	return code
+}
+
+// Evaluates the templates over the row and builds its QR Code.
+func (t *batchTemplates) build(r row, format string, options qr.Options) batchResult {
+	result := batchResult{row: r.index, name: strconv.Itoa(r.index)}
+	if r.err != nil {
+		result.err = r.err
+		return result
+	}
+
+	values := map[*template.Template]string{}
+	for _, tmpl := range []*template.Template{t.data, t.name, t.version, t.mode, t.level, t.mask, t.scale, t.quiet, t.fg, t.bg} {
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, r.values); err != nil {
+			result.err = err
+			return result
+		}
+		values[tmpl] = buffer.String()
+	}
+
+	if name := strings.TrimSpace(values[t.name]); name != "" {
+		if name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			result.err = fmt.Errorf("invalid file name: %q", name)
+			return result
+		}
+		result.name = name
+	}
+	result.name += "." + format
+
+	var mask, quiet, scale int
+	for _, integer := range []struct {
+		tmpl  *template.Template
+		value *int
+	}{{t.version, &options.Version}, {t.mask, &mask}, {t.quiet, &quiet}, {t.scale, &scale}} {
+		n, err := strconv.Atoi(strings.TrimSpace(values[integer.tmpl]))
+		if err != nil {
+			result.err = fmt.Errorf("invalid %s: %q", integer.tmpl.Name(), values[integer.tmpl])
+			return result
+		}
+		*integer.value = n
+	}
+	options.Error = strings.TrimSpace(values[t.level])
+	options.Mask = &mask
+
+	c := &config{
+		format:  format,
+		mode:    strings.TrimSpace(values[t.mode]),
+		quiet:   quiet,
+		scale:   scale,
+		fg:      strings.TrimSpace(values[t.fg]),
+		bg:      strings.TrimSpace(values[t.bg]),
+		options: options,
+	}
+	result.output, result.err = c.render(values[t.data])
+	return result
+}
+
+// Reads CSV rows. The first row holds the column names.
+func readCSV(input io.Reader, rows chan<- row) error {
+	reader := csv.NewReader(input)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for index := 1; ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return err
+		}
+		r := row{index: index, err: err}
+		if err == nil {
+			r.values = map[string]string{}
+			for i, column := range header {
+				r.values[column] = record[i]
+			}
+		}
+		rows <- r
+	}
+}
+
+// Reads JSON Lines rows, each an object of column names to values. Empty
+// lines are skipped.
+func readJSONL(input io.Reader, rows chan<- row) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, 1<<20)
+	for index := 1; scanner.Scan(); {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		r := row{index: index}
+		index++
+
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			r.err = fmt.Errorf("invalid JSON: %v", err)
+		} else {
+			r.values = map[string]string{}
+			for column, value := range object {
+				if s, ok := value.(string); ok {
+					r.values[column] = s
+				} else {
+					r.values[column] = fmt.Sprint(value)
+				}
+			}
+		}
+		rows <- r
+	}
+	return scanner.Err()
+}
+
+// Returns a function that writes a file to the output directory or zip
+// archive and a function that finishes the output.
+func openOutput(output string) (func(name string, data []byte) error, func() error, error) {
+	if strings.ToLower(filepath.Ext(output)) != ".zip" {
+		if err := os.MkdirAll(output, 0o755); err != nil {
+			return nil, nil, err
+		}
+		write := func(name string, data []byte) error {
+			return os.WriteFile(filepath.Join(output, name), data, 0o644)
+		}
+		return write, func() error { return nil }, nil
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	archive := zip.NewWriter(f)
+	write := func(name string, data []byte) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	finish := func() error {
+		if err := archive.Close(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return write, finish, nil
+}
+
+// Writes the failed rows as CSV to the report file, or lists them on stderr
+// if no report file is given.
+func writeReport(report string, failures []batchResult, stderr io.Writer) error {
+	if report == "" {
+		for _, failure := range failures {
+			fmt.Fprintf(stderr, "qr batch: row %d: %v\n", failure.row, failure.err)
+		}
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"row", "name", "error"})
+	for _, failure := range failures {
+		writer.Write([]string{strconv.Itoa(failure.row), failure.name, failure.err.Error()})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return os.WriteFile(report, buffer.Bytes(), 0o644)
+}
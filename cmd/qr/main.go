@@ -0,0 +1,260 @@
+// Command qr generates QR Codes.
+//
+// Install it with:
+//
+//	go install github.com/AlexEidt/qr/cmd/qr@latest
+//
+// The data is taken from the arguments, from the file given with -i or from
+// standard input. A single trailing newline of file and standard input data
+// is removed. Without -o the QR Code is printed to the terminal.
+//
+//	qr "HELLO WORLD"
+//	qr -level H -scale 8 -o hello.png "HELLO WORLD"
+//	echo -n "https://example.com" | qr -format svg -fg "#1d3557" > example.svg
+//
+// The batch subcommand builds a QR Code for every row of a CSV or JSON Lines
+// file. The data, the file name and the options are templates over the
+// columns of each row:
+//
+//	qr batch -data "https://example.com/assets/{{.id}}" -name "{{.id}}" -level "{{.level}}" -o tags.zip assets.csv
+//
+// To encode the text "batch", use qr -- batch.
+//
+// The exit code is 0 on success, 1 if the QR Code could not be built or
+// written and 2 for invalid flags.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlexEidt/qr"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// errUsage marks errors in the flags.
+var errUsage = errors.New("usage")
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "batch" {
+		return runBatch(args[1:], stdin, stdout, stderr)
+	}
+
+	flags := flag.NewFlagSet("qr", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: qr [flags] [data]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+
+	input := flags.String("i", "", "read the data from `file`, - for standard input")
+	output := flags.String("o", "", "write the QR Code to `file` instead of the terminal")
+	format := flags.String("format", "", "output format: png, jpg, jpeg, svg, txt or terminal (default from the -o extension)")
+	version := flags.Int("version", 0, "version between 1 and 40 (default lowest version that fits)")
+	mode := flags.String("mode", "", "mode: numeric, alphanumeric or byte (default best fit)")
+	level := flags.String("level", "L", "error correction level: L, M, Q or H")
+	mask := flags.Int("mask", -1, "mask pattern between 0 and 7, -1 for the lowest penalty")
+	scale := flags.Int("scale", 10, "size of a module in pixels")
+	quiet := flags.Int("quiet", qr.QuietZone, "width of the quiet zone in modules")
+	fg := flags.String("fg", "#000000", "color of dark modules as #rgb, #rrggbb or #rrggbbaa")
+	bg := flags.String("bg", "#ffffff", "color of light modules as #rgb, #rrggbb or #rrggbbaa")
+	boost := flags.Bool("boost", false, "raise the error correction level as far as the version allows")
+	verify := flags.Bool("verify", false, "read the QR Code back before writing it")
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	err := generate(&config{
+		args:    flags.Args(),
+		input:   *input,
+		output:  *output,
+		format:  *format,
+		mode:    *mode,
+		quiet:   *quiet,
+		scale:   *scale,
+		fg:      *fg,
+		bg:      *bg,
+		options: qr.Options{Version: *version, Error: *level, Mask: mask, BoostError: *boost, Verify: *verify},
+	}, stdin, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "qr: %v\n", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+type config struct {
+	args    []string
+	input   string
+	output  string
+	format  string
+	mode    string
+	quiet   int
+	scale   int
+	fg, bg  string
+	options qr.Options
+}
+
+func generate(c *config, stdin io.Reader, stdout io.Writer) error {
+	data, err := c.data(stdin)
+	if err != nil {
+		return err
+	}
+
+	if c.format == "" {
+		c.format = "terminal"
+		if c.output != "" {
+			c.format = strings.TrimPrefix(strings.ToLower(filepath.Ext(c.output)), ".")
+		}
+	}
+
+	output, err := c.render(data)
+	if err != nil {
+		return err
+	}
+
+	if c.output == "" {
+		_, err := stdout.Write(output)
+		return err
+	}
+	return os.WriteFile(c.output, output, 0o644)
+}
+
+// Builds the QR Code for the data and returns it in the configured format.
+func (c *config) render(data string) ([]byte, error) {
+	var err error
+	if c.options.Mode, err = parseMode(c.mode); err != nil {
+		return nil, err
+	}
+	fg, err := parseColor(c.fg)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := parseColor(c.bg)
+	if err != nil {
+		return nil, err
+	}
+	if c.quiet < 0 {
+		return nil, fmt.Errorf("%w: quiet zone must not be negative", errUsage)
+	}
+	if c.scale < 1 {
+		return nil, fmt.Errorf("%w: scale must be at least 1", errUsage)
+	}
+	switch c.format {
+	case "png", "jpg", "jpeg", "svg", "txt", "terminal":
+	default:
+		return nil, fmt.Errorf("%w: unsupported format: %q", errUsage, c.format)
+	}
+
+	code, err := qr.NewQRCode(data, &c.options)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := code.BitmapQuietZone(c.quiet)
+
+	var buffer bytes.Buffer
+	switch c.format {
+	case "txt":
+		buffer.WriteString(bitmap.String())
+	case "terminal":
+		buffer.WriteString(terminal(bitmap))
+	default:
+		if err := bitmap.Write(&buffer, c.format, c.scale, fg, bg); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Returns the data from the arguments, the input file or standard input.
+func (c *config) data(stdin io.Reader) (string, error) {
+	if len(c.args) > 0 {
+		if c.input != "" {
+			return "", fmt.Errorf("%w: data given as arguments and with -i", errUsage)
+		}
+		return strings.Join(c.args, " "), nil
+	}
+
+	var data []byte
+	var err error
+	if c.input == "" || c.input == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(c.input)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text := string(data)
+	if strings.HasSuffix(text, "\r\n") {
+		text = text[:len(text)-2]
+	} else if strings.HasSuffix(text, "\n") {
+		text = text[:len(text)-1]
+	}
+	return text, nil
+}
+
+func parseMode(mode string) (int, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return 0, nil
+	case "numeric":
+		return qr.Numeric, nil
+	case "alphanumeric", "alphanum":
+		return qr.AlphaNum, nil
+	case "byte", "binary":
+		return qr.Byte, nil
+	}
+	return 0, fmt.Errorf("%w: unsupported mode: %q", errUsage, mode)
+}
+
+func parseColor(s string) (color.Color, error) {
+	c, err := qr.ParseColor(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return c, nil
+}
+
+// Returns the bitmap as text for a terminal with two rows per line. Light
+// modules are drawn as blocks, so the QR Code is readable on a dark
+// background.
+func terminal(bitmap *qr.Bitmap) string {
+	blocks := []string{"█", "▀", "▄", " "} // Indexed by dark top and dark bottom module.
+	var builder strings.Builder
+	for y := 0; y < bitmap.Height(); y += 2 {
+		for x := 0; x < bitmap.Width(); x++ {
+			index := 0
+			if bitmap.At(x, y) {
+				index |= 2
+			}
+			// Rows beyond the bitmap are dark, like the terminal background.
+			if y+1 >= bitmap.Height() || bitmap.At(x, y+1) {
+				index |= 1
+			}
+			builder.WriteString(blocks[index])
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
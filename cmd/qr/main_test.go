@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assertEquals(actual, expected interface{}) {
+	if actual != expected {
+		panic(fmt.Sprintf("Expected %v, got %v", expected, actual))
+	}
+}
+
+func runQR(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestPNG(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "hello.png")
+	code, _, stderr := runQR("", "-o", output, "-scale", "3", "-quiet", "2", "-level", "H", "HELLO")
+	assertEquals(stderr, "")
+	assertEquals(code, 0)
+
+	f, err := os.Open(output)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		panic(err)
+	}
+	// Version 1 is 21 modules wide.
+	assertEquals(img.Bounds().Dx(), (21+2*2)*3)
+	r, g, b, _ := img.At(2*3, 2*3).RGBA()
+	assertEquals(r|g|b, uint32(0))
+}
+
+func TestFormats(t *testing.T) {
+	code, stdout, _ := runQR("", "-format", "svg", "-fg", "#123", "-bg", "#ffeeddcc", "HELLO")
+	assertEquals(code, 0)
+	assertEquals(strings.Contains(stdout, `fill="#112233"`), true)
+	assertEquals(strings.Contains(stdout, `fill="#ffeeddcc"`), true)
+
+	// Text output from standard input without the trailing newline.
+	code, stdout, _ = runQR("12345\n", "-format", "txt", "-quiet", "0")
+	assertEquals(code, 0)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	assertEquals(len(lines), 21)
+	assertEquals(lines[0][:7], "#######")
+
+	_, expected, _ := runQR("", "-format", "txt", "-quiet", "0", "12345")
+	assertEquals(stdout, expected)
+
+	// Terminal output has two rows per line.
+	code, stdout, _ = runQR("", "HELLO")
+	assertEquals(code, 0)
+	assertEquals(strings.Count(stdout, "\n"), (21+8+1)/2)
+	assertEquals(strings.Contains(stdout, "▀"), true)
+
+	input := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(input, []byte("HELLO\r\n"), 0o644); err != nil {
+		panic(err)
+	}
+	code, stdout, _ = runQR("", "-i", input)
+	assertEquals(code, 0)
+	_, expected, _ = runQR("", "HELLO")
+	assertEquals(stdout, expected)
+}
+
+func TestErrors(t *testing.T) {
+	code, _, stderr := runQR("", "-version", "1", strings.Repeat("1", 100))
+	assertEquals(code, 1)
+	assertEquals(strings.HasPrefix(stderr, "qr: data too large for version 1-L"), true)
+
+	code, _, stderr = runQR("", "-mode", "numeric", "12A")
+	assertEquals(code, 1)
+	assertEquals(strings.Contains(stderr, "offset 2"), true)
+
+	code, _, _ = runQR("", "-level", "X", "HELLO")
+	assertEquals(code, 1)
+
+	code, _, _ = runQR("", "-fg", "blue", "HELLO")
+	assertEquals(code, 2)
+	code, _, _ = runQR("", "-o", "hello.gif", "HELLO")
+	assertEquals(code, 2)
+	code, _, _ = runQR("", "-unknown", "HELLO")
+	assertEquals(code, 2)
+	code, _, _ = runQR("", "-h")
+	assertEquals(code, 0)
+}
+
+func TestBatchCSV(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "assets.csv")
+	csvData := "id,level,scale\n" +
+		"A1,H,2\n" +
+		"A2,Q,3\n" +
+		"A3,X,2\n" + // Invalid level.
+		"A1,L,2\n" + // Duplicate name.
+		"A4,L\n" // Missing column.
+	if err := os.WriteFile(input, []byte(csvData), 0o644); err != nil {
+		panic(err)
+	}
+
+	output := filepath.Join(dir, "out")
+	report := filepath.Join(dir, "report.csv")
+	code, _, stderr := runQR("", "batch",
+		"-data", "https://example.com/assets/{{.id}}",
+		"-name", "tag-{{.id}}",
+		"-level", "{{.level}}",
+		"-scale", "{{.scale}}",
+		"-workers", "3",
+		"-report", report,
+		"-o", output, input)
+	assertEquals(code, 1)
+	assertEquals(strings.Contains(stderr, "2 QR Codes generated, 3 failed"), true)
+
+	f, err := os.Open(filepath.Join(output, "tag-A2.png"))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(img.Bounds().Dx()%3, 0)
+
+	f, err = os.Open(report)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(len(records), 4)
+	assertEquals(records[1][0], "3")
+	assertEquals(strings.Contains(records[1][2], "invalid error level"), true)
+	assertEquals(records[2][0], "4")
+	assertEquals(strings.Contains(records[2][2], `"tag-A1.png" is already used by row 1`), true)
+	assertEquals(records[3][0], "5")
+}
+
+func TestBatchJSONL(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := `{"id": 1, "text": "HELLO"}` + "\n\n" + `{"id": 2, "text": "WORLD"}` + "\n" + `{"id": 3}` + "\n"
+
+	output := filepath.Join(dir, "tags.zip")
+	code, _, stderr := runQR(jsonl, "batch", "-input", "jsonl", "-format", "svg",
+		"-data", "{{.text}}", "-name", "{{.id}}", "-fg", "#00f", "-o", output)
+	assertEquals(code, 1)
+	assertEquals(strings.Contains(stderr, "row 3:"), true)
+
+	archive, err := zip.OpenReader(output)
+	if err != nil {
+		panic(err)
+	}
+	defer archive.Close()
+	names := map[string]bool{}
+	for _, file := range archive.File {
+		names[file.Name] = true
+	}
+	assertEquals(len(names), 2)
+	assertEquals(names["1.svg"] && names["2.svg"], true)
+
+	code, _, _ = runQR("", "batch", "-o", output)
+	assertEquals(code, 2)
+	code, _, _ = runQR("", "batch", "-data", "{{.id", "-o", output)
+	assertEquals(code, 2)
+	code, _, _ = runQR(`{"id": "../x"}`, "batch", "-input", "jsonl", "-data", "x", "-name", "{{.id}}", "-o", filepath.Join(dir, "out"))
+	assertEquals(code, 1)
+
+	// "batch" can still be encoded as data.
+	code, _, _ = runQR("", "-format", "txt", "--", "batch")
+	assertEquals(code, 0)
+}
@@ -0,0 +1,42 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidLevel        = errors.New("invalid error level. Must be L, M, Q or H")
+	ErrInvalidVersion      = errors.New("invalid version number. Must be between 1 and 40")
+	ErrInvalidMode         = errors.New("given mode is not supported")
+	ErrInvalidMask         = errors.New("invalid mask pattern. Must be between 0 and 7, or -1")
+	ErrInvalidFNC1         = errors.New("invalid FNC1 mode")
+	ErrInvalidAppIndicator = errors.New("invalid application indicator. Must be a letter or two digits")
+	ErrVerify              = errors.New("generated QR Code could not be read back")
+)
+
+// DataTooLargeError is returned when the data does not fit in the requested
+// version, or in any version if no version was requested.
+type DataTooLargeError struct {
+	Bits    int    // Bits needed to encode the data in Version.
+	MaxBits int    // Data capacity of Version in bits.
+	Version int    // The requested version, or the largest allowed version.
+	Level   string // Error correction level.
+}
+
+func (e *DataTooLargeError) Error() string {
+	return fmt.Sprintf("data too large for version %d-%s: %d bits, capacity is %d bits", e.Version, e.Level, e.Bits, e.MaxBits)
+}
+
+// ModeMismatchError is returned when the data cannot be encoded in the
+// requested mode.
+type ModeMismatchError struct {
+	Requested int // Requested mode.
+	Required  int // Smallest mode that can encode all of the data.
+	Offset    int // Byte offset of the first character that cannot be encoded in the requested mode.
+}
+
+func (e *ModeMismatchError) Error() string {
+	return fmt.Sprintf("could not encode data with given mode: %s mode is required for the character at offset %d",
+		modeName(e.Required), e.Offset)
+}
@@ -0,0 +1,201 @@
+package qr
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Report describes every step NewQRCode takes to build a QR Code.
+type Report struct {
+	Data     string          `json:"data"`
+	Version  int             `json:"version"`
+	Level    string          `json:"level"`
+	FNC1     int             `json:"fnc1,omitempty"`
+	Segments []SegmentReport `json:"segments"`
+	// Number of bits each version at the chosen error level can hold and
+	// the number of bits the data needs in that version.
+	Capacities []CapacityReport `json:"capacities"`
+	// Bits of the encoded segments, before termination and padding.
+	DataBits       int `json:"dataBits"`
+	TerminatorBits int `json:"terminatorBits"`
+	// Zero bits added after the terminator to reach a multiple of 8 bits.
+	AlignmentBits int `json:"alignmentBits"`
+	// Alternating 0xEC and 0x11 bytes added to fill the capacity.
+	PadBytes    int           `json:"padBytes"`
+	Blocks      []BlockReport `json:"blocks"`
+	Interleaved Codewords     `json:"interleaved"`
+	Masks       []MaskReport  `json:"masks"`
+	Mask        int           `json:"mask"`
+}
+
+type SegmentReport struct {
+	Mode       int `json:"mode"`
+	ECI        int `json:"eci,omitempty"`
+	Characters int `json:"characters"`
+	ModeBits   int `json:"modeBits"`  // Mode indicator, including the ECI header and FNC1 indicator.
+	CountBits  int `json:"countBits"` // Character count indicator, see length.
+	DataBits   int `json:"dataBits"`
+}
+
+type CapacityReport struct {
+	Version      int  `json:"version"`
+	CapacityBits int  `json:"capacityBits"`
+	RequiredBits int  `json:"requiredBits"`
+	Fits         bool `json:"fits"`
+}
+
+type BlockReport struct {
+	Data            Codewords `json:"data"`
+	ErrorCorrection Codewords `json:"errorCorrection"`
+}
+
+// MaskReport holds the penalty score of each rule for a mask pattern.
+type MaskReport struct {
+	Pattern int `json:"pattern"`
+	N1      int `json:"n1"`
+	N2      int `json:"n2"`
+	N3      int `json:"n3"`
+	N4      int `json:"n4"`
+	Total   int `json:"total"`
+}
+
+// Codewords are formatted as space separated hexadecimal bytes.
+type Codewords []byte
+
+func (c Codewords) String() string {
+	var builder strings.Builder
+	for i, b := range c {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(hex.EncodeToString([]byte{b}))
+	}
+	return builder.String()
+}
+
+func (c Codewords) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Explain builds a QR Code like NewQRCode and returns a report of each step.
+func Explain(data string, options *Options) (*Report, error) {
+	encoder := encoders.Get().(*Encoder)
+	defer encoders.Put(encoder)
+
+	report := &Report{Data: data}
+	qr, err := encoder.encode(data, options, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Mask = qr.pattern
+
+	return report, nil
+}
+
+// Records the segments and the capacity of each version once the mode and
+// version of the QR Code are known.
+func (r *Report) addVersion(qr *QRCode, segments []Segment) {
+	r.Version = qr.version
+	r.Level = qr.errorLevel
+	r.FNC1 = qr.fnc1
+	for i, segment := range segments {
+		modeBits := eciSize(segment.ECI) + 4
+		if i == 0 {
+			modeBits += qr.fnc1Size()
+		}
+		r.Segments = append(r.Segments, SegmentReport{
+			Mode:       segment.Mode,
+			ECI:        segment.ECI,
+			Characters: len(segment.Data),
+			ModeBits:   modeBits,
+			CountBits:  length(qr.version, segment.Mode),
+			DataBits:   encodedSize(segment.Mode, len(segment.Data)),
+		})
+	}
+
+	errorIndex := strings.Index("LMQH", qr.errorLevel)
+	for version := 1; version <= 40; version++ {
+		required := qr.requiredBits(version, segments)
+		bits := capacity[(version-1)*4+errorIndex]
+		r.Capacities = append(r.Capacities, CapacityReport{
+			Version:      version,
+			CapacityBits: bits,
+			RequiredBits: required,
+			Fits:         required <= bits,
+		})
+	}
+}
+
+// JSON returns the report as indented JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// String returns the report as text.
+func (r *Report) String() string {
+	var builder strings.Builder
+	printf := func(format string, args ...interface{}) {
+		fmt.Fprintf(&builder, format, args...)
+	}
+
+	printf("Data: %q\n", r.Data)
+	printf("Version: %d, Error Level: %s", r.Version, r.Level)
+	if r.FNC1 != 0 {
+		printf(", FNC1: %d", r.FNC1)
+	}
+	printf("\n\nSegments:\n")
+	for _, segment := range r.Segments {
+		printf("  %-12s %d characters: %d mode + %d count + %d data bits",
+			modeName(segment.Mode), segment.Characters, segment.ModeBits, segment.CountBits, segment.DataBits)
+		if segment.ECI != 0 {
+			printf(", ECI %d", segment.ECI)
+		}
+		printf("\n")
+	}
+
+	printf("\nCapacity (%s):\n", r.Level)
+	for _, c := range r.Capacities[:r.Version] {
+		status := "too small"
+		if c.Fits {
+			status = fmt.Sprintf("%d bits spare", c.CapacityBits-c.RequiredBits)
+		}
+		printf("  Version %2d: %5d of %5d bits, %s\n", c.Version, c.RequiredBits, c.CapacityBits, status)
+	}
+
+	printf("\nData: %d bits, terminator: %d bits, alignment: %d bits, padding: %d bytes\n",
+		r.DataBits, r.TerminatorBits, r.AlignmentBits, r.PadBytes)
+
+	printf("\nBlocks:\n")
+	for i, block := range r.Blocks {
+		printf("  Block %d: %d data, %d error correction codewords\n", i, len(block.Data), len(block.ErrorCorrection))
+		printf("    Data: %s\n", block.Data)
+		printf("    EC:   %s\n", block.ErrorCorrection)
+	}
+	printf("\nInterleaved: %s\n", r.Interleaved)
+
+	printf("\nMask penalties:\n")
+	for _, mask := range r.Masks {
+		chosen := ""
+		if mask.Pattern == r.Mask {
+			chosen = " (chosen)"
+		}
+		printf("  Mask %d: N1 %4d  N2 %4d  N3 %4d  N4 %4d  Total %5d%s\n",
+			mask.Pattern, mask.N1, mask.N2, mask.N3, mask.N4, mask.Total, chosen)
+	}
+
+	return builder.String()
+}
+
+func modeName(mode int) string {
+	switch mode {
+	case Numeric:
+		return "Numeric"
+	case AlphaNum:
+		return "Alphanumeric"
+	case Byte:
+		return "Byte"
+	}
+	return fmt.Sprintf("Mode %d", mode)
+}
@@ -0,0 +1,99 @@
+package qr
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Geo describes a location encoded as a geo: URI.
+// See: https://www.rfc-editor.org/rfc/rfc5870
+type Geo struct {
+	Latitude    float64 // Between -90 and 90.
+	Longitude   float64 // Between -180 and 180.
+	Altitude    float64 // Only included when HasAltitude is set.
+	HasAltitude bool
+	Query       string // Optional search query, appended as "?q=".
+}
+
+// Payload validates the coordinates and returns the geo: URI.
+func (g *Geo) Payload() (string, error) {
+	if err := g.validate(); err != nil {
+		return "", err
+	}
+
+	uri := "geo:" + formatCoordinate(g.Latitude) + "," + formatCoordinate(g.Longitude)
+	if g.HasAltitude {
+		uri += "," + formatCoordinate(g.Altitude)
+	}
+	if g.Query != "" {
+		uri += "?q=" + url.QueryEscape(g.Query)
+	}
+
+	return uri, nil
+}
+
+func (g *Geo) validate() error {
+	for _, v := range []float64{g.Latitude, g.Longitude, g.Altitude} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid geo coordinate: %v", v)
+		}
+	}
+	if g.Latitude < -90 || g.Latitude > 90 {
+		return fmt.Errorf("invalid latitude: %v. Must be between -90 and 90", g.Latitude)
+	}
+	if g.Longitude < -180 || g.Longitude > 180 {
+		return fmt.Errorf("invalid longitude: %v. Must be between -180 and 180", g.Longitude)
+	}
+	return nil
+}
+
+func formatCoordinate(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseGeo parses a geo: URI. URI parameters such as ";u=" are ignored.
+func ParseGeo(uri string) (*Geo, error) {
+	if !strings.HasPrefix(strings.ToLower(uri), "geo:") {
+		return nil, fmt.Errorf("invalid geo URI: %s", uri)
+	}
+	uri = uri[4:]
+
+	g := &Geo{}
+	if i := strings.Index(uri, "?"); i >= 0 {
+		query, err := url.ParseQuery(uri[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		g.Query = query.Get("q")
+		uri = uri[:i]
+	}
+	if i := strings.Index(uri, ";"); i >= 0 {
+		uri = uri[:i]
+	}
+
+	coords := strings.Split(uri, ",")
+	if len(coords) != 2 && len(coords) != 3 {
+		return nil, fmt.Errorf("invalid geo coordinates: %s", uri)
+	}
+	values := make([]float64, len(coords))
+	for i, coord := range coords {
+		v, err := strconv.ParseFloat(coord, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geo coordinate: %s", coord)
+		}
+		values[i] = v
+	}
+	g.Latitude, g.Longitude = values[0], values[1]
+	if len(values) == 3 {
+		g.Altitude, g.HasAltitude = values[2], true
+	}
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
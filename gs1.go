@@ -0,0 +1,246 @@
+package qr
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GS1Element is a single GS1 Application Identifier and its value,
+// for example {"01", "09506000134352"} for a GTIN.
+type GS1Element struct {
+	AI    string
+	Value string
+}
+
+type gs1Format struct {
+	length  int  // Exact length if fixed, maximum length otherwise.
+	fixed   bool // Value has a fixed length.
+	numeric bool // Value only contains digits.
+	check   bool // Last digit is a GS1 check digit.
+	date    bool // Value is a YYMMDD date.
+}
+
+// Formats of supported Application Identifiers.
+// See: https://ref.gs1.org/ai/
+var gs1Formats = map[string]gs1Format{
+	"00":   {length: 18, fixed: true, numeric: true, check: true}, // SSCC
+	"01":   {length: 14, fixed: true, numeric: true, check: true}, // GTIN
+	"02":   {length: 14, fixed: true, numeric: true, check: true}, // GTIN of contained trade items
+	"10":   {length: 20},                                          // Batch or lot number
+	"11":   {length: 6, fixed: true, numeric: true, date: true},   // Production date
+	"12":   {length: 6, fixed: true, numeric: true, date: true},   // Due date
+	"13":   {length: 6, fixed: true, numeric: true, date: true},   // Packaging date
+	"15":   {length: 6, fixed: true, numeric: true, date: true},   // Best before date
+	"16":   {length: 6, fixed: true, numeric: true, date: true},   // Sell by date
+	"17":   {length: 6, fixed: true, numeric: true, date: true},   // Expiration date
+	"20":   {length: 2, fixed: true, numeric: true},               // Internal product variant
+	"21":   {length: 20},                                          // Serial number
+	"22":   {length: 20},                                          // Consumer product variant
+	"235":  {length: 28},                                          // Third Party Controlled, Serialised Extension of GTIN
+	"240":  {length: 30},                                          // Additional product identification
+	"241":  {length: 30},                                          // Customer part number
+	"250":  {length: 30},                                          // Secondary serial number
+	"251":  {length: 30},                                          // Reference to source entity
+	"254":  {length: 20},                                          // GLN extension component
+	"30":   {length: 8, numeric: true},                            // Variable count of items
+	"37":   {length: 8, numeric: true},                            // Count of trade items
+	"400":  {length: 30},                                          // Customer's purchase order number
+	"401":  {length: 30},                                          // Global Identification Number for Consignment
+	"402":  {length: 17, fixed: true, numeric: true, check: true}, // Global Shipment Identification Number
+	"410":  {length: 13, fixed: true, numeric: true, check: true}, // Ship to GLN
+	"411":  {length: 13, fixed: true, numeric: true, check: true}, // Bill to GLN
+	"412":  {length: 13, fixed: true, numeric: true, check: true}, // Purchased from GLN
+	"413":  {length: 13, fixed: true, numeric: true, check: true}, // Ship for GLN
+	"414":  {length: 13, fixed: true, numeric: true, check: true}, // Physical location GLN
+	"415":  {length: 13, fixed: true, numeric: true, check: true}, // Invoicing party GLN
+	"420":  {length: 20},                                          // Ship to postal code
+	"8004": {length: 30},                                          // GIAI
+	"8020": {length: 25},                                          // Payment slip reference number
+	"8200": {length: 70},                                          // Extended packaging URL
+	"90":   {length: 30},                                          // Mutually agreed information
+}
+
+// Application Identifiers starting with these two digits have a predefined
+// length and are never followed by a Group Separator.
+var gs1Predefined = []string{
+	"00", "01", "02", "03", "04", "11", "12", "13", "14", "15", "16",
+	"17", "18", "19", "20", "31", "32", "33", "34", "35", "36", "41",
+}
+
+// GS1 AI encodable character set 82.
+var gs1Chars = "!\"%&'()*+,-./0123456789:;<=>?ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+func gs1Lookup(ai string) (gs1Format, bool) {
+	if format, ok := gs1Formats[ai]; ok {
+		return format, true
+	}
+	// Trade measures such as 3103 (net weight in kg, 3 decimal places).
+	if len(ai) == 4 && "31" <= ai[:2] && ai[:2] <= "36" && isDigit(ai[2]) && '0' <= ai[3] && ai[3] <= '5' {
+		return gs1Format{length: 6, fixed: true, numeric: true}, true
+	}
+	// Company internal information.
+	if len(ai) == 2 && "91" <= ai && ai <= "99" {
+		return gs1Format{length: 90}, true
+	}
+	return gs1Format{}, false
+}
+
+// Validate checks the value of the element against the format of its
+// Application Identifier.
+func (e GS1Element) Validate() error {
+	format, ok := gs1Lookup(e.AI)
+	if !ok {
+		return fmt.Errorf("unsupported GS1 application identifier: %s", e.AI)
+	}
+
+	if format.fixed && len(e.Value) != format.length {
+		return fmt.Errorf("GS1 AI (%s) must be %d characters long", e.AI, format.length)
+	}
+	if len(e.Value) == 0 || len(e.Value) > format.length {
+		return fmt.Errorf("GS1 AI (%s) must be between 1 and %d characters long", e.AI, format.length)
+	}
+	for i := 0; i < len(e.Value); i++ {
+		if format.numeric && !isDigit(e.Value[i]) {
+			return fmt.Errorf("GS1 AI (%s) must only contain digits", e.AI)
+		}
+		if !strings.Contains(gs1Chars, string(e.Value[i])) {
+			return fmt.Errorf("GS1 AI (%s) contains invalid character %q", e.AI, e.Value[i])
+		}
+	}
+
+	if format.check && gs1CheckDigit(e.Value[:len(e.Value)-1]) != e.Value[len(e.Value)-1] {
+		return fmt.Errorf("GS1 AI (%s) has an invalid check digit", e.AI)
+	}
+	if format.date {
+		month, _ := strconv.Atoi(e.Value[2:4])
+		day, _ := strconv.Atoi(e.Value[4:6])
+		year, _ := strconv.Atoi(e.Value[0:2])
+		// A day of "00" refers to the last day of the month.
+		if month < 1 || month > 12 || day > time.Date(2000+year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			return fmt.Errorf("GS1 AI (%s) has an invalid date: %s", e.AI, e.Value)
+		}
+	}
+
+	return nil
+}
+
+// Computes the GS1 Modulo 10 check digit.
+func gs1CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		weight := 1
+		if (len(digits)-i)%2 == 1 {
+			weight = 3
+		}
+		sum += int(digits[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// GS1ElementString validates the elements and concatenates them into a GS1
+// element string. Variable length fields are terminated with the ASCII Group
+// Separator (0x1D), unless they are the last element.
+func GS1ElementString(elements []GS1Element) (string, error) {
+	if len(elements) == 0 {
+		return "", fmt.Errorf("no GS1 elements given")
+	}
+
+	var builder strings.Builder
+	for i, element := range elements {
+		if err := element.Validate(); err != nil {
+			return "", err
+		}
+		builder.WriteString(element.AI)
+		builder.WriteString(element.Value)
+		if i < len(elements)-1 && !contains(gs1Predefined, element.AI[:2]) {
+			builder.WriteByte(0x1d)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// NewGS1QRCode builds a GS1 QR Code (FNC1 in first position) for the given
+// elements. Any FNC1 setting in options is overridden.
+func NewGS1QRCode(elements []GS1Element, options *Options) (*QRCode, error) {
+	data, err := GS1ElementString(elements)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := copyOptions(options)
+	opts.FNC1 = FNC1First
+
+	return NewQRCode(data, &opts)
+}
+
+// Primary keys supported in GS1 Digital Link URIs and their key qualifiers
+// in the order they appear in the path.
+var gs1Keys = map[string][]string{
+	"00":   {},
+	"01":   {"22", "10", "21"},
+	"414":  {"254"},
+	"8004": {},
+}
+
+// GS1DigitalLink builds a GS1 Digital Link URI such as
+// https://id.gs1.org/01/09506000134352/10/ABC?17=251231.
+// The first element must be a primary key (00, 01, 414 or 8004). Its key
+// qualifiers are placed in the path and all other elements in the query.
+// If domain is empty, https://id.gs1.org is used.
+func GS1DigitalLink(domain string, elements []GS1Element) (string, error) {
+	if domain == "" {
+		domain = "https://id.gs1.org"
+	}
+	if len(elements) == 0 {
+		return "", fmt.Errorf("no GS1 elements given")
+	}
+	qualifiers, ok := gs1Keys[elements[0].AI]
+	if !ok {
+		return "", fmt.Errorf("GS1 AI (%s) is not a supported primary key", elements[0].AI)
+	}
+
+	values := map[string]string{}
+	for _, element := range elements {
+		if err := element.Validate(); err != nil {
+			return "", err
+		}
+		if _, ok := values[element.AI]; ok {
+			return "", fmt.Errorf("duplicate GS1 AI (%s)", element.AI)
+		}
+		values[element.AI] = element.Value
+	}
+
+	path := "/" + elements[0].AI + "/" + url.PathEscape(elements[0].Value)
+	for _, qualifier := range qualifiers {
+		if value, ok := values[qualifier]; ok {
+			path += "/" + qualifier + "/" + url.PathEscape(value)
+		}
+	}
+
+	var query []string
+	for _, element := range elements[1:] {
+		if !contains(qualifiers, element.AI) {
+			query = append(query, element.AI+"="+url.QueryEscape(element.Value))
+		}
+	}
+
+	uri := strings.TrimRight(domain, "/") + path
+	if len(query) > 0 {
+		uri += "?" + strings.Join(query, "&")
+	}
+
+	return uri, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+)
+
+var mediaTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"svg":  "image/svg+xml",
+}
+
+// DataURI returns the QR Code as a base64 encoded data URI such as
+// "data:image/png;base64,...". The format is png, jpg, jpeg or svg.
+func (qr *QRCode) DataURI(format string, scale int) (string, error) {
+	mediaType, ok := mediaTypes[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+
+	var buffer bytes.Buffer
+	if err := qr.Write(&buffer, format, scale); err != nil {
+		return "", err
+	}
+
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+// SVG returns the QR Code as an SVG element to embed in HTML. The title and
+// description are added as <title> and <desc> for assistive technologies.
+func (qr *QRCode) SVG(scale int, title, description string) template.HTML {
+	var buffer bytes.Buffer
+	qr.qr.renderVector(&buffer, scale, nil, nil, svgOptions{
+		title:       title,
+		description: description,
+	})
+	return template.HTML(buffer.String())
+}
+
+// FuncMap returns template functions that build QR Codes. It can be passed
+// to Funcs of html/template and text/template.
+//
+//	qrsvg data level scale     Inline SVG element, see QRCode.SVG.
+//	qrpng data level scale     PNG data URI, for <img src="...">.
+//	qrsvguri data level scale  SVG data URI, for <img src="...">.
+//
+// For example {{ qrsvg .URL "M" 4 }}.
+func FuncMap() map[string]interface{} {
+	build := func(data, level string) (*QRCode, error) {
+		return NewQRCode(data, &Options{Error: level})
+	}
+	return map[string]interface{}{
+		"qrsvg": func(data, level string, scale int) (template.HTML, error) {
+			qr, err := build(data, level)
+			if err != nil {
+				return "", err
+			}
+			return qr.SVG(scale, "QR Code", data), nil
+		},
+		"qrpng": func(data, level string, scale int) (template.URL, error) {
+			qr, err := build(data, level)
+			if err != nil {
+				return "", err
+			}
+			uri, err := qr.DataURI("png", scale)
+			return template.URL(uri), err
+		},
+		"qrsvguri": func(data, level string, scale int) (template.URL, error) {
+			qr, err := build(data, level)
+			if err != nil {
+				return "", err
+			}
+			uri, err := qr.DataURI("svg", scale)
+			return template.URL(uri), err
+		},
+	}
+}
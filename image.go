@@ -0,0 +1,59 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+)
+
+// Image is a read-only image.Image view of a Bitmap where each module is a
+// scale x scale square. Its color model is a two color palette, so it is
+// encoded as an indexed 1-bit PNG and can be drawn with image/draw directly.
+type Image struct {
+	bitmap  *Bitmap
+	scale   int
+	palette color.Palette // Background and foreground color.
+}
+
+// Image returns an image of the bitmap with set modules in fg and unset
+// modules in bg. A nil fg defaults to black and a nil bg to white.
+func (b *Bitmap) Image(scale int, fg, bg color.Color) *Image {
+	if scale < 1 {
+		scale = 1
+	}
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	return &Image{
+		bitmap:  b,
+		scale:   scale,
+		palette: color.Palette{bg, fg},
+	}
+}
+
+// Image returns an image of the QR Code, see Bitmap.Image.
+func (qr *QRCode) Image(scale int, fg, bg color.Color) *Image {
+	return qr.qr.Image(scale, fg, bg)
+}
+
+func (img *Image) ColorModel() color.Model {
+	return img.palette
+}
+
+func (img *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, img.bitmap.Width()*img.scale, img.bitmap.Height()*img.scale)
+}
+
+func (img *Image) At(x, y int) color.Color {
+	return img.palette[img.ColorIndexAt(x, y)]
+}
+
+// ColorIndexAt returns 1 for set modules and 0 otherwise.
+func (img *Image) ColorIndexAt(x, y int) uint8 {
+	if x < 0 || y < 0 || !img.bitmap.At(x/img.scale, y/img.scale) {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,179 @@
+package qr
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OTPAuth describes a TOTP or HOTP key in the otpauth:// URI format
+// understood by authenticator apps.
+// See: https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+type OTPAuth struct {
+	Type      string // "totp" or "hotp". Defaults to "totp".
+	Issuer    string
+	Account   string
+	Secret    []byte // Raw secret, Base32 encoded without padding in the URI.
+	Algorithm string // SHA1, SHA256 or SHA512. Defaults to SHA1.
+	Digits    int    // 6 or 8. Defaults to 6.
+	Period    int    // TOTP only. Defaults to 30 seconds.
+	Counter   uint64 // HOTP only.
+}
+
+var otpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Payload validates the key parameters and returns the otpauth:// URI.
+func (o *OTPAuth) Payload() (string, error) {
+	otp := *o
+	if otp.Type == "" {
+		otp.Type = "totp"
+	}
+	if otp.Algorithm == "" {
+		otp.Algorithm = "SHA1"
+	}
+	if otp.Digits == 0 {
+		otp.Digits = 6
+	}
+	if otp.Type == "totp" && otp.Period == 0 {
+		otp.Period = 30
+	}
+	if err := otp.validate(); err != nil {
+		return "", err
+	}
+
+	label := url.PathEscape(otp.Account)
+	if otp.Issuer != "" {
+		label = url.PathEscape(otp.Issuer) + ":" + label
+	}
+
+	var query strings.Builder
+	query.WriteString("secret=" + otpEncoding.EncodeToString(otp.Secret))
+	if otp.Issuer != "" {
+		query.WriteString("&issuer=" + otpQueryEscape(otp.Issuer))
+	}
+	query.WriteString("&algorithm=" + otp.Algorithm)
+	query.WriteString("&digits=" + strconv.Itoa(otp.Digits))
+	if otp.Type == "totp" {
+		query.WriteString("&period=" + strconv.Itoa(otp.Period))
+	} else {
+		query.WriteString("&counter=" + strconv.FormatUint(otp.Counter, 10))
+	}
+
+	return fmt.Sprintf("otpauth://%s/%s?%s", otp.Type, label, query.String()), nil
+}
+
+func (o *OTPAuth) validate() error {
+	if o.Type != "totp" && o.Type != "hotp" {
+		return fmt.Errorf("invalid otp type: %s", o.Type)
+	}
+	if len(o.Secret) == 0 {
+		return fmt.Errorf("otp secret must not be empty")
+	}
+	if o.Account == "" {
+		return fmt.Errorf("otp account must not be empty")
+	}
+	// The label uses ':' to separate the issuer from the account.
+	if strings.Contains(o.Issuer, ":") || strings.Contains(o.Account, ":") {
+		return fmt.Errorf("otp issuer and account must not contain ':'")
+	}
+	switch o.Algorithm {
+	case "SHA1", "SHA256", "SHA512":
+	default:
+		return fmt.Errorf("invalid otp algorithm: %s", o.Algorithm)
+	}
+	if o.Digits != 6 && o.Digits != 8 {
+		return fmt.Errorf("invalid otp digits: %d. Must be 6 or 8", o.Digits)
+	}
+	if o.Type == "totp" && o.Period < 1 {
+		return fmt.Errorf("invalid otp period: %d", o.Period)
+	}
+	return nil
+}
+
+// Authenticator apps show '+' literally, so spaces are escaped as %20.
+func otpQueryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// NewOTPAuthQRCode builds a QR Code for the given key. otpauth:// URIs contain
+// lowercase letters, so they are always encoded in Byte mode; any other Mode in
+// options is rejected.
+func NewOTPAuthQRCode(otp *OTPAuth, options *Options) (*QRCode, error) {
+	payload, err := otp.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := copyOptions(options)
+	if opts.Mode != 0 && opts.Mode != Byte {
+		return nil, fmt.Errorf("%w: otpauth URIs must be encoded in Byte mode", ErrInvalidMode)
+	}
+	opts.Mode = Byte
+
+	return NewQRCode(payload, &opts)
+}
+
+// ParseOTPAuth parses an otpauth:// URI.
+func ParseOTPAuth(uri string) (*OTPAuth, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("invalid otpauth scheme: %s", u.Scheme)
+	}
+
+	otp := &OTPAuth{Type: u.Host}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(label, ":"); i >= 0 {
+		otp.Issuer, otp.Account = label[:i], strings.TrimLeft(label[i+1:], " ")
+	} else {
+		otp.Account = label
+	}
+
+	query := u.Query()
+	otp.Secret, err = otpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(query.Get("secret"), "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otp secret: %v", err)
+	}
+	if issuer := query.Get("issuer"); issuer != "" {
+		if otp.Issuer != "" && otp.Issuer != issuer {
+			return nil, fmt.Errorf("otp issuer mismatch: %s and %s", otp.Issuer, issuer)
+		}
+		otp.Issuer = issuer
+	}
+
+	otp.Algorithm = "SHA1"
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		otp.Algorithm = strings.ToUpper(algorithm)
+	}
+	otp.Digits = 6
+	if digits := query.Get("digits"); digits != "" {
+		if otp.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid otp digits: %s", digits)
+		}
+	}
+	if otp.Type == "totp" {
+		otp.Period = 30
+		if period := query.Get("period"); period != "" {
+			if otp.Period, err = strconv.Atoi(period); err != nil {
+				return nil, fmt.Errorf("invalid otp period: %s", period)
+			}
+		}
+	}
+	if otp.Type == "hotp" {
+		counter := query.Get("counter")
+		if otp.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid otp counter: %s", counter)
+		}
+	}
+
+	if err := otp.validate(); err != nil {
+		return nil, err
+	}
+
+	return otp, nil
+}
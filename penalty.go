@@ -0,0 +1,230 @@
+package qr
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Penalty weights from ISO/IEC 18004 section 7.8.3.
+const (
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
+
+type maskPlanes struct {
+	once       sync.Once
+	planes     [8]*Bitmap // Data modules inverted by each mask pattern.
+	transposed [8]*Bitmap
+}
+
+// Mask planes for each version, computed on first use.
+var planes [41]maskPlanes
+
+// Returns the mask planes for the version of the QR Code. Requires the
+// function pattern mask of the QR Code.
+func (qr *QRCode) maskPlanes() *maskPlanes {
+	p := &planes[qr.version]
+	p.once.Do(func() {
+		for mask := 0; mask < 8; mask++ {
+			maskFunc := maskPattern(mask)
+			plane := NewBitmap(qr.size, qr.size)
+			for y := 0; y < qr.size; y++ {
+				for x := 0; x < qr.size; x++ {
+					if qr.mask.At(x, y) && maskFunc(x, y) {
+						plane.Set(x, y, true)
+					}
+				}
+			}
+			p.planes[mask] = plane
+			p.transposed[mask] = NewBitmap(0, 0)
+			plane.transpose(p.transposed[mask])
+		}
+	})
+	return p
+}
+
+// Places the unmasked data once and evaluates each mask pattern by XORing its
+// precomputed plane onto the result. Columns are scored as the rows of the
+// transposed symbol.
+func (e *Encoder) findBestMaskPattern(qr *QRCode, bitstream *Buffer, report *Report) int {
+	p := qr.maskPlanes()
+
+	e.base.copyFrom(qr.qr)
+	qr.placeBits(e.base, bitstream, -1)
+	e.base.transpose(e.transposed[0])
+
+	bestMask, bestScore := 0, 0
+
+	for mask := 0; mask < 8; mask++ {
+		template, transposed := e.template, e.transposed[1]
+
+		template.copyFrom(e.base)
+		template.xor(p.planes[mask])
+		qr.addFormatInformation(template, mask)
+
+		transposed.copyFrom(e.transposed[0])
+		transposed.xor(p.transposed[mask])
+		// Format Information lies in row and column 8.
+		for i := 0; i < qr.size; i++ {
+			transposed.Set(8, i, template.At(i, 8))
+			transposed.Set(i, 8, template.At(8, i))
+		}
+
+		penalty := maskPenalty(template, transposed)
+		score := penalty[0] + penalty[1] + penalty[2] + penalty[3]
+		if report != nil {
+			report.Masks = append(report.Masks, MaskReport{
+				Pattern: mask,
+				N1:      penalty[0],
+				N2:      penalty[1],
+				N3:      penalty[2],
+				N4:      penalty[3],
+				Total:   score,
+			})
+		}
+
+		if mask == 0 || score < bestScore {
+			bestScore = score
+			bestMask = mask
+		}
+	}
+
+	return bestMask
+}
+
+// Bits of a single row of a QR Code, least significant bit first.
+// The largest QR Code is 177 modules wide.
+type bitRow [3]uint64
+
+// Returns the row shifted by k < 64 towards bit 0, so that bit x of the
+// result is bit x + k of r.
+func (r *bitRow) shr(k uint) bitRow {
+	return bitRow{
+		r[0]>>k | r[1]<<(64-k),
+		r[1]>>k | r[2]<<(64-k),
+		r[2] >> k,
+	}
+}
+
+// Returns the row shifted by k < 64 away from bit 0, so that bit x of the
+// result is bit x - k of r.
+func (r *bitRow) shl(k uint) bitRow {
+	return bitRow{
+		r[0] << k,
+		r[1]<<k | r[0]>>(64-k),
+		r[2]<<k | r[1]>>(64-k),
+	}
+}
+
+// Returns a row with the first n bits set.
+func ones(n int) bitRow {
+	var r bitRow
+	for i := range r {
+		switch {
+		case n >= (i+1)*64:
+			r[i] = ^uint64(0)
+		case n > i*64:
+			r[i] = 1<<(n-i*64) - 1
+		}
+	}
+	return r
+}
+
+func (r *bitRow) count() int {
+	return bits.OnesCount64(r[0]) + bits.OnesCount64(r[1]) + bits.OnesCount64(r[2])
+}
+
+// Evaluates the four mask penalty rules of ISO/IEC 18004 on the symbol
+// (without quiet zone) and returns the score of each rule. transposed is the
+// transposed symbol, used to score columns like rows.
+func maskPenalty(bitmap, transposed *Bitmap) [4]int {
+	var penalty [4]int
+	size := bitmap.Width()
+
+	valid := ones(size)
+	var rows [2]bitRow
+	for _, b := range []*Bitmap{bitmap, transposed} {
+		for y := 0; y < size; y++ {
+			curr := &rows[y%2]
+			b.rowBits(y, curr[:])
+
+			penalty[0] += runPenalty(curr, size)
+			penalty[2] += finderPenalty(curr, &valid)
+
+			if y > 0 && b == bitmap {
+				penalty[1] += blockPenalty(&rows[(y+1)%2], curr, size)
+			}
+		}
+	}
+
+	// Rule #4
+	// Proportion of dark modules. Each full 5% deviation from 50% scores N4.
+	total := size * size
+	penalty[3] = abs(bitmap.popCount()*2-total) * 10 / total * penaltyN4
+
+	return penalty
+}
+
+// Rule #1
+// Adjacent modules in a row or column of the same color. A run of 5 + i
+// modules scores N1 + i.
+func runPenalty(r *bitRow, size int) int {
+	penalty := 0
+	// Bit x is set where module x differs from module x + 1.
+	shifted := r.shr(1)
+	boundaries := ones(size - 1)
+	start := 0
+	for i := range boundaries {
+		changes := (r[i] ^ shifted[i]) & boundaries[i]
+		for changes != 0 {
+			end := i*64 + bits.TrailingZeros64(changes) + 1
+			if end-start >= 5 {
+				penalty += penaltyN1 + end - start - 5
+			}
+			start = end
+			changes &= changes - 1
+		}
+	}
+	if size-start >= 5 {
+		penalty += penaltyN1 + size - start - 5
+	}
+	return penalty
+}
+
+// Rule #2
+// Each 2x2 block of modules of the same color scores N2.
+func blockPenalty(top, bottom *bitRow, size int) int {
+	topShifted, bottomShifted := top.shr(1), bottom.shr(1)
+	valid := ones(size - 1)
+	var same bitRow
+	for i := range same {
+		same[i] = ^(top[i] ^ bottom[i]) & ^(top[i] ^ topShifted[i]) & ^(bottom[i] ^ bottomShifted[i]) & valid[i]
+	}
+	return same.count() * penaltyN2
+}
+
+// Rule #3
+// Each 1:1:3:1:1 (dark:light:dark:dark:dark:light:dark) pattern in a row or
+// column that is preceded or followed by 4 light modules scores N3. Modules
+// outside of the symbol belong to the light quiet zone.
+func finderPenalty(r *bitRow, valid *bitRow) int {
+	var shr [11]bitRow
+	for k := 1; k < len(shr); k++ {
+		shr[k] = r.shr(uint(k))
+	}
+	var shl [5]bitRow
+	for k := 1; k < len(shl); k++ {
+		shl[k] = r.shl(uint(k))
+	}
+
+	var matches bitRow
+	for i := range matches {
+		pattern := r[i] & ^shr[1][i] & shr[2][i] & shr[3][i] & shr[4][i] & ^shr[5][i] & shr[6][i]
+		before := ^(shl[1][i] | shl[2][i] | shl[3][i] | shl[4][i])
+		after := ^(shr[7][i] | shr[8][i] | shr[9][i] | shr[10][i])
+		matches[i] = pattern & (before | after) & valid[i]
+	}
+	return matches.count() * penaltyN3
+}
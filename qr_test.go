@@ -1,8 +1,25 @@
 package qr
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	htmltemplate "html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"math/rand"
+	"strings"
 	"testing"
+	texttemplate "text/template"
+	"time"
 )
 
 func assertEquals(actual, expected interface{}) {
@@ -73,3 +90,1420 @@ func TestByteMode(t *testing.T) {
 
 	assertEquals(qr.mode, Byte)
 }
+
+func TestOTPAuth(t *testing.T) {
+	otp := &OTPAuth{
+		Issuer:    "ACME Co",
+		Account:   "jane@example.com",
+		Secret:    []byte("12345678901234567890"),
+		Algorithm: "SHA256",
+	}
+	uri, err := otp.Payload()
+	if err != nil {
+		panic(err)
+	}
+	expected := "otpauth://totp/ACME%20Co:jane@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" +
+		"&issuer=ACME%20Co&algorithm=SHA256&digits=6&period=30"
+	assertEquals(uri, expected)
+
+	parsed, err := ParseOTPAuth(uri)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.Type, "totp")
+	assertEquals(parsed.Issuer, otp.Issuer)
+	assertEquals(parsed.Account, otp.Account)
+	assertEquals(string(parsed.Secret), string(otp.Secret))
+	assertEquals(parsed.Algorithm, "SHA256")
+	assertEquals(parsed.Digits, 6)
+	assertEquals(parsed.Period, 30)
+
+	qr, err := NewOTPAuthQRCode(otp, &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Mode(), Byte)
+
+	_, err = NewOTPAuthQRCode(otp, &Options{Mode: AlphaNum})
+	assertEquals(errors.Is(err, ErrInvalidMode), true)
+
+	hotp := &OTPAuth{Type: "hotp", Account: "bob", Secret: []byte{1, 2, 3}, Digits: 8, Counter: 42}
+	uri, err = hotp.Payload()
+	if err != nil {
+		panic(err)
+	}
+	parsed, err = ParseOTPAuth(uri)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.Counter, uint64(42))
+	assertEquals(parsed.Digits, 8)
+
+	for _, invalid := range []*OTPAuth{
+		{Account: "bob"},
+		{Secret: []byte{1}},
+		{Account: "bob", Secret: []byte{1}, Digits: 7},
+		{Account: "bob", Secret: []byte{1}, Algorithm: "MD5"},
+		{Issuer: "a:b", Account: "bob", Secret: []byte{1}},
+	} {
+		_, err := invalid.Payload()
+		assertEquals(err != nil, true)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	event := &Event{
+		UID:         "poster-2024@example.com",
+		Summary:     "Launch party; drinks, snacks",
+		Location:    "Hall 3\\Main Stage",
+		Description: strings.Repeat("A long description with ümlauts. ", 4) + "\nSee you there!",
+		Start:       time.Date(2024, 5, 17, 19, 30, 0, 0, berlin),
+		End:         time.Date(2024, 5, 17, 23, 0, 0, 0, time.UTC),
+	}
+	payload, err := event.Payload()
+	if err != nil {
+		panic(err)
+	}
+	for _, line := range strings.Split(payload, "\r\n") {
+		if len(line) > 75 {
+			panic(fmt.Sprintf("line exceeds 75 octets: %q", line))
+		}
+	}
+	assertEquals(strings.Contains(payload, "DTSTART;TZID=Europe/Berlin:20240517T193000\r\n"), true)
+	assertEquals(strings.Contains(payload, "DTEND:20240517T230000Z\r\n"), true)
+	assertEquals(strings.Contains(payload, `SUMMARY:Launch party\; drinks\, snacks`), true)
+
+	parsed, err := ParseEvent(payload)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.UID, event.UID)
+	assertEquals(parsed.Summary, event.Summary)
+	assertEquals(parsed.Location, event.Location)
+	assertEquals(parsed.Description, event.Description)
+	assertEquals(parsed.Start.Equal(event.Start), true)
+	assertEquals(parsed.Start.Location().String(), "Europe/Berlin")
+	assertEquals(parsed.End.Equal(event.End), true)
+
+	if _, err := NewQRCode(payload, nil); err != nil {
+		panic(err)
+	}
+
+	_, err = (&Event{Summary: "No start"}).Payload()
+	assertEquals(err != nil, true)
+
+	// Fixed offsets are converted to UTC, since they have no loadable TZID.
+	event = &Event{
+		Summary: "Fixed offset",
+		Start:   time.Date(2024, 5, 17, 19, 30, 0, 0, time.FixedZone("+0100", 3600)),
+		Stamp:   time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	}
+	payload, err = event.Payload()
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(strings.Contains(payload, "DTSTART:20240517T183000Z\r\n"), true)
+	assertEquals(strings.Contains(payload, "DTSTAMP:20240501T080000Z\r\n"), true)
+	parsed, err = ParseEvent(payload)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.Start.Equal(event.Start), true)
+	assertEquals(parsed.Stamp.Equal(event.Stamp), true)
+
+	// Properties outside of the VEVENT and in nested components are ignored.
+	calendar := "BEGIN:VCALENDAR\r\nSUMMARY:Calendar\r\n" +
+		strings.Replace(payload, "END:VEVENT", "BEGIN:VALARM\r\nDESCRIPTION:Alarm\r\nEND:VALARM\r\nEND:VEVENT", 1) +
+		"END:VCALENDAR\r\n"
+	parsed, err = ParseEvent(calendar)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.Summary, "Fixed offset")
+	assertEquals(parsed.Description, "")
+	_, err = ParseEvent("SUMMARY:Outside\r\nDTSTART:20240517T183000Z\r\n")
+	assertEquals(err != nil, true)
+}
+
+func TestGeo(t *testing.T) {
+	geo := &Geo{Latitude: 48.2010, Longitude: 16.3695, Altitude: 183, HasAltitude: true, Query: "Stephansplatz 1"}
+	uri, err := geo.Payload()
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(uri, "geo:48.201,16.3695,183?q=Stephansplatz+1")
+
+	parsed, err := ParseGeo(uri)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(*parsed, *geo)
+
+	parsed, err = ParseGeo("geo:-33.8688,151.2093;u=35")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(parsed.Latitude, -33.8688)
+	assertEquals(parsed.HasAltitude, false)
+
+	for _, invalid := range []*Geo{{Latitude: 91}, {Longitude: -180.5}, {Latitude: math.NaN()}} {
+		_, err := invalid.Payload()
+		assertEquals(err != nil, true)
+	}
+	_, err = ParseGeo("geo:1,2,3,4")
+	assertEquals(err != nil, true)
+}
+
+func TestGS1(t *testing.T) {
+	elements := []GS1Element{
+		{"01", "09506000134352"},
+		{"10", "ABC123"},
+		{"17", "251231"},
+		{"21", "12345"},
+	}
+	data, err := GS1ElementString(elements)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(data, "010950600013435210ABC123\x1d172512312112345")
+
+	qr, err := NewGS1QRCode(elements, nil)
+	if err != nil {
+		panic(err)
+	}
+	// The Group Separator is encoded as "%" in Alphanumeric mode.
+	assertEquals(qr.Mode(), AlphaNum)
+	assertEquals(qr.fnc1, FNC1First)
+
+	qr, err = NewGS1QRCode([]GS1Element{{"01", "09506000134352"}, {"10", "abc"}}, nil)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Mode(), Byte)
+
+	for _, invalid := range []GS1Element{
+		{"01", "09506000134353"}, // Wrong check digit.
+		{"01", "0950600013435"},  // Too short.
+		{"17", "251301"},         // Invalid month.
+		{"17", "250230"},         // Invalid day.
+		{"10", "ABC|123"},        // Invalid character.
+		{"99999", "1"},           // Unknown AI.
+	} {
+		assertEquals(invalid.Validate() != nil, true)
+	}
+	assertEquals(GS1Element{"17", "240200"}.Validate(), nil)
+	assertEquals(GS1Element{"3103", "000750"}.Validate(), nil)
+
+	uri, err := GS1DigitalLink("", []GS1Element{
+		{"01", "09506000134352"},
+		{"17", "251231"},
+		{"21", "SN1/2"},
+		{"10", "ABC123"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(uri, "https://id.gs1.org/01/09506000134352/10/ABC123/21/SN1%2F2?17=251231")
+
+	_, err = GS1DigitalLink("https://example.com", []GS1Element{{"10", "ABC"}})
+	assertEquals(err != nil, true)
+}
+
+func TestFNC1(t *testing.T) {
+	gs1, err := NewQRCode("01095060001343521012%", &Options{FNC1: FNC1First})
+	if err != nil {
+		panic(err)
+	}
+	plain, err := NewQRCode("01095060001343521012%", nil)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(gs1.Mode(), AlphaNum)
+	assertEquals(gs1.Bitmap().Equal(plain.Bitmap()), false)
+
+	qr, err := NewQRCode("ABC", &Options{FNC1: FNC1Second, AppIndicator: "a"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.indicator, 197)
+	qr, err = NewQRCode("ABC", &Options{FNC1: FNC1Second, AppIndicator: "37"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.indicator, 37)
+
+	_, err = NewQRCode("ABC", &Options{FNC1: FNC1Second, AppIndicator: "ab"})
+	assertEquals(err != nil, true)
+	_, err = NewQRCode("1234\x1d5", &Options{FNC1: FNC1First, Mode: Numeric})
+	assertEquals(err != nil, true)
+}
+
+func TestBase45(t *testing.T) {
+	// Test vectors from RFC 9285.
+	vectors := map[string]string{
+		"AB":           "BB8",
+		"Hello!!":      "%69 VD92EX0",
+		"base-45":      "UJCLQE7W581",
+		"ietf!":        "QED8WEX0",
+		"":             "",
+		"\x00":         "00",
+		"\xff\xff\xff": "FGWU5",
+	}
+	for data, encoded := range vectors {
+		assertEquals(Base45Encode([]byte(data)), encoded)
+		decoded, err := Base45Decode(encoded)
+		if err != nil {
+			panic(err)
+		}
+		assertEquals(string(decoded), data)
+	}
+
+	for _, invalid := range []string{"GGW", "A", "abc", "ZZ"} {
+		_, err := Base45Decode(invalid)
+		assertEquals(err != nil, true)
+	}
+}
+
+func TestCompressedPayload(t *testing.T) {
+	data := []byte(strings.Repeat(`{"name":"Jane Doe","dose":2,"valid":true}`, 8))
+
+	qr, err := NewCompressedQRCode(data, &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Mode(), AlphaNum)
+
+	raw, err := NewQRCode(string(data), &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version() < raw.Version(), true)
+
+	payload, err := CompressPayload(data)
+	if err != nil {
+		panic(err)
+	}
+	decompressed, err := DecompressPayload(payload)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(string(decompressed), string(data))
+
+	_, err = NewCompressedQRCode(data, &Options{Mode: Numeric})
+	assertEquals(errors.Is(err, ErrInvalidMode), true)
+}
+
+func TestSignedPayload(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	keys := map[string]ed25519.PublicKey{"ticket-2024": public}
+	payload := []byte("EVENT:42;SEAT:A17;NAME:JANE DOE")
+
+	for _, base45 := range []bool{false, true} {
+		signer := &Signer{KeyID: "ticket-2024", Key: private, Base45: base45}
+
+		data, err := signer.Payload(payload)
+		if err != nil {
+			panic(err)
+		}
+		verified, keyID, err := VerifySigned(data, keys)
+		if err != nil {
+			panic(err)
+		}
+		assertEquals(string(verified), string(payload))
+		assertEquals(keyID, "ticket-2024")
+
+		// Tamper with the payload.
+		envelope, _ := signer.Sign(payload)
+		envelope[len(envelope)-ed25519.SignatureSize-1] ^= 1
+		_, _, err = VerifySigned(string(envelope), keys)
+		assertEquals(err != nil, true)
+
+		versions, err := signer.Versions(payload)
+		if err != nil {
+			panic(err)
+		}
+		qr, err := signer.QRCode(payload, &Options{Error: "Q"})
+		if err != nil {
+			panic(err)
+		}
+		assertEquals(qr.Version(), versions["Q"])
+		assertEquals(versions["L"] <= versions["H"], true)
+	}
+
+	_, _, err = VerifySigned(string([]byte{1, 3, 'f', 'o', 'o'})+strings.Repeat("x", 64), keys)
+	assertEquals(err != nil, true)
+}
+
+func penaltyOf(bitmap *Bitmap) [4]int {
+	transposed := NewBitmap(0, 0)
+	bitmap.transpose(transposed)
+	return maskPenalty(bitmap, transposed)
+}
+
+// Straightforward module by module implementation of the mask penalty rules.
+func referencePenalty(bitmap *Bitmap) [4]int {
+	var penalty [4]int
+	size := bitmap.Width()
+
+	// Modules outside of the symbol belong to the light quiet zone.
+	at := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < size && y < size && bitmap.At(x, y)
+	}
+
+	// Rule #1
+	// Adjacent modules in a row or column of the same color. A run of 5 + i
+	// modules scores N1 + i.
+	for i := 0; i < size; i++ {
+		rowCount, colCount := 1, 1
+		for j := 1; j <= size; j++ {
+			if j < size && at(j, i) == at(j-1, i) {
+				rowCount++
+			} else {
+				if rowCount >= 5 {
+					penalty[0] += penaltyN1 + rowCount - 5
+				}
+				rowCount = 1
+			}
+			if j < size && at(i, j) == at(i, j-1) {
+				colCount++
+			} else {
+				if colCount >= 5 {
+					penalty[0] += penaltyN1 + colCount - 5
+				}
+				colCount = 1
+			}
+		}
+	}
+
+	// Rule #2
+	// Each 2x2 block of modules of the same color scores N2.
+	for y := 0; y < size-1; y++ {
+		for x := 0; x < size-1; x++ {
+			curr := at(x, y)
+			if curr == at(x+1, y) && curr == at(x, y+1) && curr == at(x+1, y+1) {
+				penalty[1] += penaltyN2
+			}
+		}
+	}
+
+	// Rule #3
+	// Each 1:1:3:1:1 (dark:light:dark:dark:dark:light:dark) pattern in a row or
+	// column that is preceded or followed by 4 light modules scores N3.
+	pattern := []bool{true, false, true, true, true, false, true}
+	light := func(get func(int) bool, from int) bool {
+		for k := from; k < from+4; k++ {
+			if get(k) {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < size; i++ {
+		row := func(k int) bool { return at(k, i) }
+		col := func(k int) bool { return at(i, k) }
+		for _, get := range []func(int) bool{row, col} {
+			for j := 0; j <= size-len(pattern); j++ {
+				match := true
+				for k := 0; k < len(pattern) && match; k++ {
+					match = get(j+k) == pattern[k]
+				}
+				if match && (light(get, j-4) || light(get, j+len(pattern))) {
+					penalty[2] += penaltyN3
+				}
+			}
+		}
+	}
+
+	// Rule #4
+	// Proportion of dark modules. Each full 5% deviation from 50% scores N4.
+	darkCount := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if bitmap.At(x, y) {
+				darkCount++
+			}
+		}
+	}
+	total := size * size
+	penalty[3] = abs(darkCount*2-total) * 10 / total * penaltyN4
+
+	return penalty
+}
+
+func TestMaskPenalty(t *testing.T) {
+	// All light modules.
+	bitmap := NewBitmap(21, 21)
+	// N1: 42 runs of 21. N2: 20x20 blocks. N4: 0% dark.
+	assertEquals(penaltyOf(bitmap), [4]int{42 * (3 + 16), 400 * 3, 0, 100})
+
+	// Checkerboard.
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 21; x++ {
+			bitmap.Set(x, y, (x+y)%2 == 0)
+		}
+	}
+	assertEquals(penaltyOf(bitmap), [4]int{0, 0, 0, 0})
+
+	// A single finder-like pattern at the start of a row, followed by light modules.
+	bitmap = NewBitmap(21, 21)
+	for _, x := range []int{0, 2, 3, 4, 6} {
+		bitmap.Set(x, 10, true)
+	}
+	// N1: Row 10 has a run of 14, the other rows a run of 21. The five columns
+	// containing a dark module have two runs of 10, the others a run of 21.
+	// N2: The dark modules break 14 of the 2x2 blocks in rows 9 to 11.
+	// N4: 5 of 441 modules are dark, 48% deviation.
+	assertEquals(penaltyOf(bitmap), [4]int{12 + 20*19 + 5*16 + 16*19, (400 - 14) * 3, 40, 90})
+
+	// The pattern is not preceded or followed by 4 light modules.
+	bitmap = NewBitmap(15, 15)
+	for _, x := range []int{2, 4, 6, 7, 8, 10, 12} {
+		bitmap.Set(x, 0, true)
+	}
+	assertEquals(penaltyOf(bitmap)[2], 0)
+	// Patterns with 4 light modules on both sides are counted once.
+	bitmap = NewBitmap(15, 15)
+	for _, y := range []int{4, 6, 7, 8, 10} {
+		bitmap.Set(7, y, true)
+	}
+	assertEquals(penaltyOf(bitmap)[2], 40)
+}
+
+// Reference vectors for N1, N2 and N4 from the mask penalty tests of
+// github.com/boombuler/barcode (qr/qrcode_test.go, v1.0.1). Its N3 vectors
+// are not used since it does not count patterns touching the Quiet Zone.
+func TestMaskPenaltyExternal(t *testing.T) {
+	bitmap := NewBitmap(7, 7)
+	assertEquals(penaltyOf(bitmap)[0], 70)
+	bitmap.Set(0, 0, true)
+	assertEquals(penaltyOf(bitmap)[0], 68)
+	bitmap.Set(0, 6, true)
+	assertEquals(penaltyOf(bitmap)[0], 66)
+
+	bitmap = NewBitmap(3, 3)
+	assertEquals(penaltyOf(bitmap)[1], 12)
+	bitmap.Set(0, 0, true)
+	bitmap.Set(1, 1, true)
+	bitmap.Set(2, 0, true)
+	assertEquals(penaltyOf(bitmap)[1], 0)
+	bitmap.Set(1, 1, false)
+	assertEquals(penaltyOf(bitmap)[1], 6)
+
+	bitmap = NewBitmap(3, 3)
+	assertEquals(penaltyOf(bitmap)[3], 100)
+	for i, expected := range []int{70, 50, 30, 10, 10} {
+		bitmap.Set(i/3, i%3, true)
+		assertEquals(penaltyOf(bitmap)[3], expected)
+	}
+	bitmap = NewBitmap(2, 2)
+	bitmap.Set(0, 0, true)
+	bitmap.Set(1, 0, true)
+	assertEquals(penaltyOf(bitmap)[3], 0)
+}
+
+func TestBuffer(t *testing.T) {
+	buffer := NewBuffer()
+	buffer.AppendBits(0b0100, 4)
+	buffer.AppendBits(11, 9)
+	buffer.AppendBits(0b101, 3)
+	buffer.AppendBits(0b1, 1)
+	assertEquals(buffer.Len(), 17)
+	assertEquals(buffer.String(), "01000000010111011")
+	assertEquals(fmt.Sprint(buffer.Bytes()), fmt.Sprint([]byte{0b01000000, 0b01011101, 0b10000000}))
+	assertEquals(buffer.At(13), true)
+	assertEquals(buffer.At(14), false)
+
+	buffer.Reset()
+	buffer.AppendBits(0b11101100, 8)
+	assertEquals(buffer.String(), "11101100")
+	assertEquals(fmt.Sprint(buffer.Bytes()), fmt.Sprint([]byte{0b11101100}))
+
+	// Deprecated wrappers.
+	buffer.Clear()
+	buffer.Write("101")
+	buffer.Add(0b01, 2)
+	assertEquals(buffer.Size(), 5)
+	assertEquals(buffer.String(), "10101")
+	assertEquals(fmt.Sprint(buffer.Bytes()), fmt.Sprint([]byte{0b10101000}))
+}
+
+func BenchmarkBuffer(b *testing.B) {
+	b.ReportAllocs()
+	buffer := NewBuffer()
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		for j := 0; j < 1000; j++ {
+			buffer.AppendBits(j, 11)
+		}
+	}
+}
+
+func BenchmarkNewQRCode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewQRCode("https://example.com/asset/1234567890?batch=ABCDEF", &Options{Error: "M"}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkNewQRCodeLarge(b *testing.B) {
+	b.ReportAllocs()
+	data := strings.Repeat("abcdefghijklmnopqrstuvwxyz", 46)
+	for i := 0; i < b.N; i++ {
+		if _, err := NewQRCode(data, nil); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	encoder := NewEncoder()
+	for _, data := range []string{"HELLO WORLD", strings.Repeat("x", 500), "0123456789", "HELLO WORLD"} {
+		qr, err := encoder.Encode(data, &Options{Error: "Q"})
+		if err != nil {
+			panic(err)
+		}
+		expected, err := NewQRCode(data, &Options{Error: "Q"})
+		if err != nil {
+			panic(err)
+		}
+		assertEquals(qr.qr.Equal(expected.qr), true)
+	}
+}
+
+func TestGenerateBatch(t *testing.T) {
+	inputs := make(chan Item)
+	go func() {
+		defer close(inputs)
+		for i := 0; i < 100; i++ {
+			item := Item{Data: fmt.Sprintf("ASSET-%05d", i)}
+			if i%10 == 0 {
+				item.Options = &Options{Error: "X"}
+			}
+			inputs <- item
+		}
+	}()
+
+	count := 0
+	for result := range GenerateBatch(context.Background(), inputs, 4) {
+		assertEquals(result.Index, count)
+		if count%10 == 0 {
+			assertEquals(result.Err != nil, true)
+		} else {
+			if result.Err != nil {
+				panic(result.Err)
+			}
+			assertEquals(result.QRCode.Mode(), AlphaNum)
+		}
+		count++
+	}
+	assertEquals(count, 100)
+
+	// Cancellation closes the results channel while inputs are still open.
+	ctx, cancel := context.WithCancel(context.Background())
+	inputs = make(chan Item)
+	results := GenerateBatch(ctx, inputs, 2)
+	inputs <- Item{Data: "1"}
+	<-results
+	cancel()
+	for range results {
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	b.ReportAllocs()
+	encoder := NewEncoder()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoder.Encode("https://example.com/asset/1234567890?batch=ABCDEF", &Options{Error: "M"}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkGenerateBatch(b *testing.B) {
+	b.ReportAllocs()
+	inputs := make(chan Item)
+	go func() {
+		defer close(inputs)
+		for i := 0; i < b.N; i++ {
+			inputs <- Item{Data: fmt.Sprintf("https://example.com/asset/%010d", i)}
+		}
+	}()
+	for result := range GenerateBatch(context.Background(), inputs, 0) {
+		if result.Err != nil {
+			panic(result.Err)
+		}
+	}
+}
+
+func TestMaskPenaltyReference(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, size := range []int{21, 25, 57, 63, 64, 65, 101, 127, 128, 129, 177} {
+		for i := 0; i < 5; i++ {
+			bitmap := NewBitmap(size, size)
+			// Sparse and dense bitmaps produce long runs and finder-like patterns.
+			density := []int{2, 3, 8, 50}[i%4]
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					bitmap.Set(x, y, random.Intn(density) == 0)
+				}
+			}
+			assertEquals(penaltyOf(bitmap), referencePenalty(bitmap))
+		}
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	bitmap := NewBitmap(3, 2)
+	bitmap.Set(0, 0, true)
+	bitmap.Set(2, 1, true)
+	bitmap.Set(-1, 0, true) // Ignored.
+	bitmap.Set(3, 1, true)  // Ignored.
+	assertEquals(bitmap.String(), "#..\n..#\n")
+	assertEquals(bitmap.At(-1, 1), false)
+	assertEquals(bitmap.At(3, 0), false)
+	assertEquals(bitmap.In(2, 1), true)
+	assertEquals(bitmap.PopCount(), 2)
+	assertEquals(fmt.Sprint(bitmap.Row(1)), "[false false true]")
+
+	assertEquals(bitmap.Rotate90().String(), ".#\n..\n#.\n")
+	assertEquals(bitmap.FlipH().String(), "..#\n#..\n")
+	assertEquals(bitmap.FlipV().String(), "..#\n#..\n")
+	assertEquals(bitmap.Crop(-1, 0, 3, 3).String(), ".#.\n...\n...\n")
+	empty := bitmap.Crop(0, 0, -2, 3)
+	assertEquals(empty.Width(), 0)
+	assertEquals(empty.Height(), 3)
+	assertEquals(empty.At(0, 0), false)
+	assertEquals(bitmap.Scale(2).String(), "##....\n##....\n....##\n....##\n")
+
+	inverted := bitmap.Copy()
+	inverted.Invert()
+	assertEquals(inverted.PopCount(), 4)
+	assertEquals(inverted.Equal(bitmap), false)
+
+	other := inverted.Copy()
+	other.Xor(bitmap)
+	assertEquals(other.PopCount(), 6)
+	other.And(bitmap)
+	assertEquals(other.Equal(bitmap), true)
+	other.Or(inverted)
+	assertEquals(other.PopCount(), 6)
+
+	assertEquals(NewBitmapFromImage(bitmap.Gray()).Equal(bitmap), true)
+	assertEquals(NewBitmapFromImage(bitmap.Paletted()).Equal(bitmap), true)
+
+	// Word-level operations on bitmaps with rows spanning multiple words.
+	qr, err := NewQRCode("Bitmap operations", &Options{Version: 10})
+	if err != nil {
+		panic(err)
+	}
+	symbol := qr.Bitmap()
+	assertEquals(symbol.FlipV().FlipV().Equal(symbol), true)
+	assertEquals(symbol.FlipH().FlipV().Equal(symbol.Rotate90().Rotate90()), true)
+	scaled := symbol.Scale(3)
+	for y := 0; y < scaled.Height(); y++ {
+		for x := 0; x < scaled.Width(); x++ {
+			assertEquals(scaled.At(x, y), symbol.At(x/3, y/3))
+		}
+	}
+	assertEquals(symbol.Scale(3).Crop(0, 0, symbol.Width()*3, symbol.Height()*3).Equal(symbol.Scale(3)), true)
+
+	// Word-level transforms agree with the module by module definitions.
+	random := rand.New(rand.NewSource(1))
+	for _, width := range []int{1, 7, 63, 64, 65, 130} {
+		bitmap := NewBitmap(width, 5)
+		for y := 0; y < 5; y++ {
+			for x := 0; x < width; x++ {
+				bitmap.Set(x, y, random.Intn(2) == 0)
+			}
+		}
+		rotated, flipped := bitmap.Rotate90(), bitmap.FlipH()
+		for y := 0; y < 5; y++ {
+			for x := 0; x < width; x++ {
+				assertEquals(rotated.At(4-y, x), bitmap.At(x, y))
+				assertEquals(flipped.At(width-1-x, y), bitmap.At(x, y))
+			}
+		}
+		assertEquals(rotated.PopCount(), bitmap.PopCount())
+		assertEquals(flipped.PopCount(), bitmap.PopCount())
+	}
+
+	assertEquals(qr.BitmapQuietZone(QuietZone).Equal(symbol), true)
+	assertEquals(qr.BitmapQuietZone(-1).Width(), symbol.Width()-2*QuietZone)
+	wide := qr.BitmapQuietZone(6)
+	assertEquals(wide.Width(), symbol.Width()+4)
+	assertEquals(wide.Crop(2, 2, symbol.Width(), symbol.Height()).Equal(symbol), true)
+	assertEquals(wide.PopCount(), symbol.PopCount())
+}
+
+func TestImage(t *testing.T) {
+	qr, err := NewQRCode("image.Image", nil)
+	if err != nil {
+		panic(err)
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	img := qr.Image(3, red, nil)
+	size := qr.Bitmap().Width()
+	assertEquals(img.Bounds(), image.Rect(0, 0, size*3, size*3))
+	assertEquals(img.At(4*3, 4*3), color.Color(red)) // Top left position pattern.
+	assertEquals(img.At(0, 0), color.Color(color.White))
+
+	// Draw the QR Code onto an existing image.
+	canvas := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(canvas, image.Rect(50, 50, 200, 200), img, image.Point{}, draw.Src)
+	assertEquals(canvas.At(50+4*3, 50+4*3), color.Color(red))
+	assertEquals(canvas.At(0, 0), color.Color(color.RGBA{}))
+
+	var buffer bytes.Buffer
+	if err := qr.Write(&buffer, "png", 2); err != nil {
+		panic(err)
+	}
+	decoded, err := png.Decode(&buffer)
+	if err != nil {
+		panic(err)
+	}
+	paletted, ok := decoded.(*image.Paletted)
+	assertEquals(ok, true)
+	assertEquals(len(paletted.Palette), 2)
+	assertEquals(NewBitmapFromImage(paletted).Equal(qr.Bitmap().Scale(2)), true)
+
+	assertEquals(qr.Write(&buffer, "gif", 2) != nil, true)
+
+	var svg, colored bytes.Buffer
+	if err := qr.Write(&svg, "svg", 2); err != nil {
+		panic(err)
+	}
+	if err := qr.Bitmap().Write(&colored, "svg", 2, color.RGBA{0x11, 0x22, 0x33, 0xff}, color.NRGBA{0xff, 0xff, 0xff, 0x80}); err != nil {
+		panic(err)
+	}
+	assertEquals(strings.Count(colored.String(), `fill="#112233"`), strings.Count(svg.String(), `fill="#000"`))
+	assertEquals(strings.Contains(colored.String(), `fill="#ffffff80"`), true)
+
+	// Scales below 1 are treated as 1 in every format.
+	var small, one bytes.Buffer
+	for _, format := range []string{"png", "svg"} {
+		small.Reset()
+		one.Reset()
+		if err := qr.Bitmap().Write(&small, format, 0, nil, nil); err != nil {
+			panic(err)
+		}
+		if err := qr.Bitmap().Write(&one, format, 1, nil, nil); err != nil {
+			panic(err)
+		}
+		assertEquals(small.String(), one.String())
+	}
+	assertEquals(qr.SVG(-1, "QR", ""), qr.SVG(1, "QR", ""))
+
+	c, err := ParseColor("#1d3557")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(hexColor(c), "#1d3557")
+	_, err = ParseColor("f0a8")
+	assertEquals(err != nil, true)
+	c, err = ParseColor("fa0")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(hexColor(c), "#ffaa00")
+}
+
+func TestRoles(t *testing.T) {
+	remainder := func(version int) int {
+		switch {
+		case version >= 2 && version <= 6:
+			return 7
+		case version >= 14 && version <= 20, version >= 28 && version <= 34:
+			return 3
+		case version >= 21 && version <= 27:
+			return 4
+		}
+		return 0
+	}
+
+	encoder := NewEncoder()
+	for version := 1; version <= 40; version++ {
+		level := string("LMQH"[version%4])
+		qr, err := encoder.Encode("ROLES", &Options{Version: version, Error: level})
+		if err != nil {
+			panic(err)
+		}
+		roles := qr.Roles()
+		bitmap := qr.Bitmap()
+		mask := maskPattern(qr.pattern)
+
+		counts := map[Role]int{}
+		for y, row := range roles {
+			for x, module := range row {
+				counts[module.Role]++
+				sx, sy := x-QuietZone, y-QuietZone
+				// The data region matches the function pattern mask of the Encoder.
+				isData := module.Role == RoleData || module.Role == RoleErrorCorrection || module.Role == RoleRemainder
+				assertEquals(isData, encoder.function.In(sx, sy) && encoder.function.At(sx, sy))
+
+				var codeword byte
+				switch module.Role {
+				case RoleData:
+					codeword = encoder.dataBlocks[module.Block][module.Index]
+				case RoleErrorCorrection:
+					codeword = encoder.errorBlocks[module.Block][module.Index]
+				default:
+					continue
+				}
+				dark := codeword&(1<<module.Bit) != 0
+				assertEquals(bitmap.At(x, y), dark != mask(sx, sy))
+			}
+		}
+
+		index := (version-1)*4 + strings.Index("LMQH", level)
+		codewords := blocks[index][0] * blocks[index][1]
+		if len(blocks[index]) > 3 {
+			codewords += blocks[index][3] * blocks[index][4]
+		}
+		assertEquals(counts[RoleData], capacity[index])
+		assertEquals(counts[RoleErrorCorrection], codewords*8-capacity[index])
+		assertEquals(counts[RoleRemainder], remainder(version))
+		assertEquals(counts[RoleFinder], 3*49)
+		assertEquals(counts[RoleDarkModule], 1)
+		assertEquals(counts[RoleFormat], 30)
+		if version >= 7 {
+			assertEquals(counts[RoleVersion], 36)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	// Example from the QR Code Tutorial at thonky.com.
+	report, err := Explain("HELLO WORLD", &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(report.Version, 1)
+	assertEquals(len(report.Segments), 1)
+	assertEquals(report.Segments[0], SegmentReport{Mode: AlphaNum, Characters: 11, ModeBits: 4, CountBits: 9, DataBits: 61})
+	assertEquals(report.DataBits, 74)
+	assertEquals(report.TerminatorBits, 4)
+	assertEquals(report.AlignmentBits, 2)
+	assertEquals(report.PadBytes, 6)
+	assertEquals(len(report.Blocks), 1)
+	assertEquals(report.Blocks[0].Data.String(), "20 5b 0b 78 d1 72 dc 4d 43 40 ec 11 ec 11 ec 11")
+	assertEquals(fmt.Sprint([]byte(report.Blocks[0].ErrorCorrection)), "[196 35 39 119 235 215 231 226 93 23]")
+	assertEquals(len(report.Interleaved), 26)
+	assertEquals(len(report.Masks), 8)
+	assertEquals(report.Capacities[0], CapacityReport{Version: 1, CapacityBits: 128, RequiredBits: 74, Fits: true})
+
+	qr, err := NewQRCode("HELLO WORLD", &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(report.Mask, qr.pattern)
+	for _, mask := range report.Masks {
+		assertEquals(mask.Total >= report.Masks[report.Mask].Total, true)
+		assertEquals(mask.Total, mask.N1+mask.N2+mask.N3+mask.N4)
+	}
+
+	text := report.String()
+	assertEquals(strings.Contains(text, "Alphanumeric"), true)
+	assertEquals(strings.Contains(text, "(chosen)"), true)
+
+	encoded, err := report.JSON()
+	if err != nil {
+		panic(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		panic(err)
+	}
+	assertEquals(decoded["interleaved"], report.Interleaved.String())
+
+	// The report shows why a larger version was chosen.
+	report, err = Explain(strings.Repeat("A", 30), &Options{Error: "H"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(report.Version, 3)
+	assertEquals(report.Capacities[1].Fits, false)
+
+	_, err = Explain("", &Options{Error: "X"})
+	assertEquals(err != nil, true)
+}
+
+func TestCapacity(t *testing.T) {
+	// Values from the character capacity table of ISO/IEC 18004.
+	for _, c := range []struct {
+		version  int
+		level    string
+		numeric  int
+		alphanum int
+		byte     int
+	}{
+		{1, "L", 41, 25, 17},
+		{1, "H", 17, 10, 7},
+		{10, "M", 513, 311, 213},
+		{40, "L", 7089, 4296, 2953},
+		{40, "H", 3057, 1852, 1273},
+	} {
+		for mode, expected := range map[int]int{Numeric: c.numeric, AlphaNum: c.alphanum, Byte: c.byte} {
+			chars, err := MaxChars(c.version, c.level, mode)
+			if err != nil {
+				panic(err)
+			}
+			assertEquals(chars, expected)
+
+			// The maximum fits, one more character does not.
+			data := strings.Repeat(map[int]string{Numeric: "1", AlphaNum: "A", Byte: "a"}[mode], chars)
+			assertEquals(FitsIn(data, c.version, c.level), true)
+			assertEquals(FitsIn(data+data[:1], c.version, c.level), false)
+		}
+	}
+
+	version, remaining, err := MinVersion("HELLO WORLD", "M")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(version, 1)
+	assertEquals(remaining, 128-74)
+
+	chars, err := Remaining("HELLO WORLD", 1, "M")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(chars, Chars{Numeric: 20 - 11, AlphaNum: 20 - 11, Byte: 14 - 11})
+
+	chars, err = Remaining("", 1, "L")
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(chars, Chars{Numeric: 41, AlphaNum: 25, Byte: 17})
+
+	_, err = MaxChars(41, "L", Byte)
+	assertEquals(err != nil, true)
+	_, err = MaxChars(1, "LM", Byte)
+	assertEquals(err != nil, true)
+	_, _, err = MinVersion(strings.Repeat("a", 3000), "L")
+	assertEquals(err != nil, true)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewQRCode("QR", &Options{Error: "LM"})
+	assertEquals(errors.Is(err, ErrInvalidLevel), true)
+	_, err = NewQRCode("QR", &Options{Version: 41})
+	assertEquals(errors.Is(err, ErrInvalidVersion), true)
+	_, err = NewQRCode("QR", &Options{Mode: 3})
+	assertEquals(errors.Is(err, ErrInvalidMode), true)
+	_, err = NewQRCode("QR", &Options{FNC1: 7})
+	assertEquals(errors.Is(err, ErrInvalidFNC1), true)
+	_, err = NewQRCode("QR", &Options{FNC1: FNC1Second, AppIndicator: "A1"})
+	assertEquals(errors.Is(err, ErrInvalidAppIndicator), true)
+	_, err = MaxChars(1, "X", Byte)
+	assertEquals(errors.Is(err, ErrInvalidLevel), true)
+
+	var large *DataTooLargeError
+	_, err = NewQRCode(strings.Repeat("1", 42), &Options{Version: 1, Error: "M"})
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(*large, DataTooLargeError{Bits: 4 + 10 + 140, MaxBits: 128, Version: 1, Level: "M"})
+
+	_, err = NewQRCode(strings.Repeat("a", 3000), nil)
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(large.Version, 40)
+	assertEquals(large.Bits > large.MaxBits, true)
+
+	_, _, err = MinVersion(strings.Repeat("a", 3000), "L")
+	assertEquals(errors.As(err, &large), true)
+
+	var mismatch *ModeMismatchError
+	_, err = NewQRCode("123ABc", &Options{Mode: AlphaNum})
+	assertEquals(errors.As(err, &mismatch), true)
+	assertEquals(*mismatch, ModeMismatchError{Requested: AlphaNum, Required: Byte, Offset: 5})
+
+	for _, mode := range []int{Numeric, AlphaNum, Byte} {
+		qr, err := NewQRCode("", &Options{Mode: mode, Verify: true})
+		if err != nil {
+			panic(err)
+		}
+		assertEquals(qr.Mode(), mode)
+	}
+
+	_, err = NewQRCode("12A", &Options{Mode: Numeric})
+	assertEquals(errors.As(err, &mismatch), true)
+	assertEquals(mismatch.Offset, 2)
+
+	// The Group Separator can be encoded in Alphanumeric mode with FNC1.
+	_, err = NewQRCode("10AB\x1d21a", &Options{Mode: AlphaNum, FNC1: FNC1First})
+	assertEquals(errors.As(err, &mismatch), true)
+	assertEquals(mismatch.Offset, 7)
+}
+
+func TestBinary(t *testing.T) {
+	data := []byte("0123456789")
+	qr, err := NewQRCodeBytes(data, nil)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Mode(), Byte)
+
+	expected, err := NewQRCode(string(data), &Options{Mode: Byte})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Bitmap().Equal(expected.Bitmap()), true)
+
+	_, err = NewQRCodeBytes(data, &Options{Mode: Numeric})
+	assertEquals(errors.Is(err, ErrInvalidMode), true)
+
+	qr, err = NewQRCodeReader(bytes.NewReader([]byte{0x00, 0xff, 0x1d, 0x80}), &Options{Error: "H"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 1)
+	assertEquals(qr.Mode(), Byte)
+
+	// 17 bytes fit in 1-L, the reader must stop after the 18th.
+	reader := &countingReader{}
+	_, err = NewQRCodeReader(reader, &Options{Version: 1})
+	var large *DataTooLargeError
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(large.Version, 1)
+	assertEquals(reader.n, 18)
+
+	// 32 bytes fit in 2-L, the reader must stop after the 33rd.
+	reader = &countingReader{}
+	_, err = NewQRCodeReader(reader, &Options{MaxVersion: 2})
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(large.Version, 2)
+	assertEquals(reader.n, 33)
+
+	_, err = NewQRCodeReader(reader, &Options{Version: 41})
+	assertEquals(errors.Is(err, ErrInvalidVersion), true)
+}
+
+// countingReader is an endless reader that counts the bytes read.
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestSegments(t *testing.T) {
+	qr, err := NewQRCodeFromSegments([]Segment{{Mode: AlphaNum, Data: "HELLO WORLD"}}, &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	expected, err := NewQRCode("HELLO WORLD", &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Bitmap().Equal(expected.Bitmap()), true)
+
+	encoder := NewEncoder()
+	qr, err = encoder.EncodeSegments([]Segment{
+		{Mode: Numeric, Data: "123"},
+		{Mode: Byte, Data: "a", ECI: 26},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 1)
+	assertEquals(qr.Mode(), Byte)
+	bits := "0001" + "0000000011" + "0001111011" + // Numeric "123"
+		"0111" + "00011010" + // ECI 26
+		"0100" + "00000001" + "01100001" + // Byte "a"
+		"0000" // Terminator
+	assertEquals(encoder.buffer.String()[:len(bits)], bits)
+
+	for _, eci := range []int{1, 127, 128, 16383, 16384, 999999} {
+		buffer := NewBuffer()
+		addECI(buffer, eci)
+		assertEquals(buffer.Len(), eciSize(eci))
+	}
+	buffer := NewBuffer()
+	addECI(buffer, 200)
+	assertEquals(buffer.String(), "0111"+"10"+"00000011001000")
+
+	var mismatch *ModeMismatchError
+	_, err = NewQRCodeFromSegments([]Segment{{Mode: Numeric, Data: "12"}, {Mode: AlphaNum, Data: "AB c"}}, nil)
+	assertEquals(errors.As(err, &mismatch), true)
+	assertEquals(*mismatch, ModeMismatchError{Requested: AlphaNum, Required: Byte, Offset: 5})
+
+	_, err = NewQRCodeFromSegments([]Segment{{Mode: 3, Data: "12"}}, nil)
+	assertEquals(errors.Is(err, ErrInvalidMode), true)
+	_, err = NewQRCodeFromSegments([]Segment{{Mode: Byte, Data: "a", ECI: 1000000}}, nil)
+	assertEquals(err != nil, true)
+	_, err = NewQRCodeFromSegments(nil, nil)
+	assertEquals(err != nil, true)
+
+	var large *DataTooLargeError
+	_, err = NewQRCodeFromSegments([]Segment{
+		{Mode: Numeric, Data: strings.Repeat("1", 20)},
+		{Mode: Numeric, Data: strings.Repeat("1", 20)},
+	}, &Options{Version: 1, Error: "H"})
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(large.Bits, 2*(4+10+67))
+}
+
+func TestBoostError(t *testing.T) {
+	// 1-H holds 10 Alphanumeric characters.
+	qr, err := NewQRCode("HELLO WORLD", &Options{BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 1)
+	assertEquals(qr.ErrorLevel(), "Q")
+
+	qr, err = NewQRCode("HELLO", &Options{BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.ErrorLevel(), "H")
+
+	// 1-L holds 25 Alphanumeric characters, 1-M 20 and 1-Q 16.
+	qr, err = NewQRCode(strings.Repeat("A", 18), &Options{BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 1)
+	assertEquals(qr.ErrorLevel(), "M")
+
+	// The version is not grown to reach a higher level.
+	qr, err = NewQRCode(strings.Repeat("A", 25), &Options{BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 1)
+	assertEquals(qr.ErrorLevel(), "L")
+
+	// A fixed version can hold a higher level.
+	qr, err = NewQRCode(strings.Repeat("A", 25), &Options{Version: 2, Error: "M", BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.ErrorLevel(), "Q")
+
+	qr, err = NewQRCodeFromSegments([]Segment{{Mode: Numeric, Data: "1234"}}, &Options{BoostError: true})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.ErrorLevel(), "H")
+}
+
+func TestVersionRangeAndMask(t *testing.T) {
+	qr, err := NewQRCode("HELLO", &Options{MinVersion: 3, MaxVersion: 10})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 3)
+
+	// 4-L holds 114 Alphanumeric characters.
+	qr, err = NewQRCode(strings.Repeat("A", 100), &Options{MinVersion: 3, MaxVersion: 10})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(qr.Version(), 4)
+
+	var large *DataTooLargeError
+	_, err = NewQRCode(strings.Repeat("A", 500), &Options{MaxVersion: 10})
+	assertEquals(errors.As(err, &large), true)
+	assertEquals(large.Version, 10)
+
+	_, err = NewQRCode("HELLO", &Options{MinVersion: 5, MaxVersion: 4})
+	assertEquals(errors.Is(err, ErrInvalidVersion), true)
+	_, err = NewQRCode("HELLO", &Options{Version: 2, MinVersion: 3})
+	assertEquals(errors.Is(err, ErrInvalidVersion), true)
+	_, err = NewQRCode("HELLO", &Options{MaxVersion: 41})
+	assertEquals(errors.Is(err, ErrInvalidVersion), true)
+
+	auto, err := NewQRCode("HELLO WORLD", nil)
+	if err != nil {
+		panic(err)
+	}
+	for mask := -1; mask < 8; mask++ {
+		m := mask
+		qr, err := NewQRCode("HELLO WORLD", &Options{Mask: &m})
+		if err != nil {
+			panic(err)
+		}
+		if mask < 0 {
+			assertEquals(qr.Mask(), auto.Mask())
+		} else {
+			assertEquals(qr.Mask(), mask)
+		}
+		assertEquals(qr.Bitmap().Equal(auto.Bitmap()), qr.Mask() == auto.Mask())
+	}
+
+	invalid := 8
+	_, err = NewQRCode("HELLO", &Options{Mask: &invalid})
+	assertEquals(errors.Is(err, ErrInvalidMask), true)
+
+	// The report still scores every mask.
+	mask := 3
+	report, err := Explain("HELLO WORLD", &Options{Mask: &mask})
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(report.Mask, 3)
+	assertEquals(len(report.Masks), 8)
+}
+
+func TestVerify(t *testing.T) {
+	inputs := []string{
+		"HELLO WORLD",
+		"0123456789012",
+		"https://example.com/?q=1",
+		"",
+		strings.Repeat("8", 2000),
+		strings.Repeat("AB:", 400),
+		strings.Repeat("\x00\xff", 600),
+	}
+	for _, data := range inputs {
+		for _, level := range []string{"L", "M", "Q", "H"} {
+			if _, _, err := MinVersion(data, level); err != nil {
+				continue
+			}
+			for mask := 0; mask < 8; mask += 3 {
+				m := mask
+				if _, err := NewQRCode(data, &Options{Error: level, Mask: &m, Verify: true}); err != nil {
+					panic(err)
+				}
+			}
+		}
+	}
+
+	if _, err := NewQRCode("10ABC\x1d21%1", &Options{FNC1: FNC1First, Verify: true}); err != nil {
+		panic(err)
+	}
+	if _, err := NewQRCode("ABC", &Options{FNC1: FNC1Second, AppIndicator: "37", Verify: true}); err != nil {
+		panic(err)
+	}
+	for _, eci := range []int{3, 200, 20000} {
+		_, err := NewQRCodeFromSegments([]Segment{
+			{Mode: Numeric, Data: "123"},
+			{Mode: Byte, Data: "\xc3\xbc", ECI: eci},
+		}, &Options{Version: 7, Verify: true})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Flipping a data module breaks the error correction.
+	qr, err := NewQRCode("HELLO WORLD", &Options{Error: "M"})
+	if err != nil {
+		panic(err)
+	}
+	segments := []Segment{{Mode: AlphaNum, Data: "HELLO WORLD"}}
+	assertEquals(qr.verify(segments), nil)
+	x, y := QuietZone+qr.size-1, QuietZone+qr.size-1
+	qr.qr.Set(x, y, !qr.qr.At(x, y))
+	assertEquals(errors.Is(qr.verify(segments), ErrVerify), true)
+
+	qr.qr.Set(x, y, !qr.qr.At(x, y))
+	assertEquals(errors.Is(qr.verify([]Segment{{Mode: AlphaNum, Data: "HELLO WORLE"}}), ErrVerify), true)
+
+	qr.qr.Set(QuietZone+8, QuietZone, !qr.qr.At(QuietZone+8, QuietZone))
+	assertEquals(errors.Is(qr.verify(segments), ErrVerify), true)
+}
+
+func TestHTML(t *testing.T) {
+	qr, err := NewQRCode("HELLO", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	uri, err := qr.DataURI("png", 2)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(strings.HasPrefix(uri, "data:image/png;base64,"), true)
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/png;base64,"))
+	if err != nil {
+		panic(err)
+	}
+	img, err := png.Decode(bytes.NewReader(decoded))
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(NewBitmapFromImage(img).Equal(qr.Bitmap().Scale(2)), true)
+
+	uri, err = qr.DataURI("svg", 2)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(strings.HasPrefix(uri, "data:image/svg+xml;base64,"), true)
+	standalone, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/svg+xml;base64,"))
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(strings.HasPrefix(string(standalone), `<svg version="1.1" xmlns="http://www.w3.org/2000/svg" width="58" height="58" viewBox="0 0 58 58" role="img">`), true)
+	_, err = qr.DataURI("gif", 2)
+	assertEquals(err != nil, true)
+
+	svg := string(qr.SVG(3, "Ticket", "Seat <12> & 13"))
+	assertEquals(strings.HasPrefix(svg, `<svg version="1.1" xmlns="http://www.w3.org/2000/svg" width="87" height="87" viewBox="0 0 87 87" role="img">`), true)
+	assertEquals(strings.Contains(svg, "<title>Ticket</title><desc>Seat &lt;12&gt; &amp; 13</desc>"), true)
+
+	page := `<img src="{{ qrpng .URL "M" 2 }}">{{ qrsvg .URL "M" 2 }}`
+	data := map[string]string{"URL": "https://example.com/?a=1&b=2"}
+	html := htmltemplate.Must(htmltemplate.New("page").Funcs(FuncMap()).Parse(page))
+	var buffer bytes.Buffer
+	if err := html.Execute(&buffer, data); err != nil {
+		panic(err)
+	}
+	assertEquals(strings.HasPrefix(buffer.String(), `<img src="data:image/png;base64,`), true)
+	assertEquals(strings.Contains(buffer.String(), `<desc>https://example.com/?a=1&amp;b=2</desc>`), true)
+
+	text := texttemplate.Must(texttemplate.New("page").Funcs(FuncMap()).Parse(`{{ qrsvguri .URL "Q" 1 }}`))
+	buffer.Reset()
+	if err := text.Execute(&buffer, data); err != nil {
+		panic(err)
+	}
+	assertEquals(strings.HasPrefix(buffer.String(), "data:image/svg+xml;base64,"), true)
+
+	assertEquals(html.Execute(&buffer, map[string]string{"URL": strings.Repeat("a", 3000)}) != nil, true)
+}
+
+func TestAnimate(t *testing.T) {
+	qr, err := NewQRCode("HELLO", &Options{Version: 7})
+	if err != nil {
+		panic(err)
+	}
+
+	var buffer bytes.Buffer
+	options := &AnimationOptions{Scale: 2, DataFrames: 8, MaskFrames: 5, LoopCount: -1}
+	if err := qr.Animate(&buffer, options); err != nil {
+		panic(err)
+	}
+	animation, err := gif.DecodeAll(&buffer)
+	if err != nil {
+		panic(err)
+	}
+	// Empty symbol, 4 function pattern stages, data and mask.
+	assertEquals(len(animation.Image), 1+4+8+5)
+	assertEquals(animation.LoopCount, -1)
+
+	canvas := image.NewPaletted(animation.Image[0].Bounds(), animation.Image[0].Palette)
+	first := NewBitmapFromImage(animation.Image[0])
+	assertEquals(first.PopCount(), 0)
+	for i, frame := range animation.Image {
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+		if i == 1+4+8-1 {
+			// All data bits are placed, but not masked yet.
+			mask := maskPattern(qr.Mask())
+			y, x := QuietZone+qr.size-1, QuietZone+qr.size-1
+			dark := canvas.ColorIndexAt(x*2, y*2) == animationDark
+			assertEquals(dark, qr.qr.At(x, y) != mask(x-QuietZone, y-QuietZone))
+		}
+	}
+	assertEquals(NewBitmapFromImage(canvas).Equal(qr.Bitmap().Scale(2)), true)
+	assertEquals(animation.Delay[len(animation.Delay)-1], 240)
+}
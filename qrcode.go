@@ -2,8 +2,8 @@ package qr
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -12,19 +12,53 @@ const (
 	Byte     = 4
 )
 
+// FNC1 mode indicators.
+const (
+	FNC1First  = 5 // GS1 formatted data.
+	FNC1Second = 9 // Data formatted according to an AIM application indicator.
+)
+
+// QuietZone is the width in modules of the light border around the QR Code.
+const QuietZone = 4
+
+// Character set of Alphanumeric mode. The index of each character is its value.
+const alphanumChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
 type QRCode struct {
 	version    int
 	size       int
 	mode       int
 	errorLevel string
+	fnc1       int
+	indicator  int     // Application Indicator for FNC1 in second position.
+	pattern    int     // Mask pattern.
 	qr         *Bitmap // The QR Code.
 	mask       *Bitmap // The QR Code mask, used to track all functional patterns.
 }
 
 type Options struct {
 	Version int
-	Mode    int
-	Error   string
+	// MinVersion and MaxVersion limit the version chosen for the data if
+	// Version is not given. Zero means no limit.
+	MinVersion int
+	MaxVersion int
+	Mode       int
+	Error      string
+	// FNC1 is either FNC1First or FNC1Second. In FNC1 mode, the ASCII Group
+	// Separator (0x1D) in the data marks the end of a variable length field.
+	FNC1 int
+	// AppIndicator is the AIM Application Indicator used with FNC1Second.
+	// Either a single letter or two digits.
+	AppIndicator string
+	// BoostError raises the error level as far as possible without growing
+	// the version.
+	BoostError bool
+	// Mask is the mask pattern between 0 and 7. If Mask is nil or -1, the
+	// mask pattern with the lowest penalty is chosen.
+	Mask *int
+	// Verify reads the QR Code back after building it and returns an error
+	// wrapping ErrVerify if it does not hold the data.
+	Verify bool
 }
 
 func (qr *QRCode) Version() int {
@@ -39,82 +73,249 @@ func (qr *QRCode) ErrorLevel() string {
 	return qr.errorLevel
 }
 
+// Mask returns the mask pattern of the QR Code.
+func (qr *QRCode) Mask() int {
+	return qr.pattern
+}
+
 func (qr *QRCode) Bitmap() *Bitmap {
 	return qr.qr.Copy()
 }
 
+// BitmapQuietZone returns the QR Code with a quiet zone of the given width
+// instead of the default QuietZone.
+func (qr *QRCode) BitmapQuietZone(width int) *Bitmap {
+	width = max(width, 0)
+	size := qr.size + 2*width
+	return qr.qr.Crop(QuietZone-width, QuietZone-width, size, size)
+}
+
+// Returns a copy of options that can be changed without affecting the
+// caller, or the default options if options is nil.
+func copyOptions(options *Options) Options {
+	if options == nil {
+		return Options{}
+	}
+	return *options
+}
+
 func NewQRCode(data string, options *Options) (*QRCode, error) {
-	qr := &QRCode{}
+	encoder := encoders.Get().(*Encoder)
+	defer encoders.Put(encoder)
+	return encoder.Encode(data, options)
+}
+
+// Encoder builds QR Codes and keeps its scratch memory between calls, so
+// that encoding many QR Codes allocates little more than the results.
+// An Encoder must not be used concurrently.
+type Encoder struct {
+	buffer      *Buffer // Data codewords.
+	stream      *Buffer // Interleaved data and error correction codewords.
+	symbol      *Bitmap // QR Code without Quiet Zone.
+	function    *Bitmap // Function pattern mask.
+	base        *Bitmap // Unmasked QR Code without Format Information.
+	template    *Bitmap // Mask pattern candidate.
+	transposed  [2]*Bitmap
+	dataBlocks  [][]byte // Slices of the data codewords.
+	errorBlocks [][]byte // Slices of ecc.
+	ecc         []byte
+	segments    []Segment
+}
+
+var encoders = sync.Pool{
+	New: func() interface{} { return NewEncoder() },
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{
+		buffer:   NewBuffer(),
+		stream:   NewBuffer(),
+		symbol:   NewBitmap(0, 0),
+		function: NewBitmap(0, 0),
+		base:     NewBitmap(0, 0),
+		template: NewBitmap(0, 0),
+		transposed: [2]*Bitmap{
+			NewBitmap(0, 0),
+			NewBitmap(0, 0),
+		},
+	}
+}
+
+// Encode builds a QR Code like NewQRCode.
+func (e *Encoder) Encode(data string, options *Options) (*QRCode, error) {
+	return e.encode(data, options, nil)
+}
+
+// Builds the QR Code and records each step in report, if it is not nil.
+func (e *Encoder) encode(data string, options *Options, report *Report) (*QRCode, error) {
+	qr, err := newQRCode(options)
+	if err != nil {
+		return nil, err
+	}
 
 	if options == nil {
 		options = &Options{}
 	}
 
-	qr.errorLevel = "L"
-	if options.Error != "" {
-		if !strings.Contains("LMQH", options.Error) {
-			return nil, fmt.Errorf("invalid error level: %s", options.Error)
+	original := data
+	if qr.fnc1 != 0 && options.Mode != Byte {
+		// In Numeric and Alphanumeric mode the Group Separator is
+		// represented by "%" and a literal "%" by "%%".
+		if escaped := escapeFNC1(data); findMode(escaped) != Byte {
+			data = escaped
 		}
-		qr.errorLevel = options.Error
 	}
 
-	qr.version = options.Version
 	qr.mode = findMode(data)
 
 	if options.Mode != 0 {
 		switch options.Mode {
 		case Numeric, AlphaNum, Byte:
-			if options.Mode >= qr.mode {
+			// Empty data can be encoded in every mode.
+			if options.Mode >= qr.mode || data == "" {
 				qr.mode = options.Mode
 			} else {
-				return nil, fmt.Errorf("could not encode data with given mode")
+				return nil, &ModeMismatchError{
+					Requested: options.Mode,
+					Required:  qr.mode,
+					Offset:    modeOffset(original, options.Mode, qr.fnc1 != 0),
+				}
 			}
 		default:
-			return nil, fmt.Errorf("given mode is not supported")
+			return nil, fmt.Errorf("%w: %d", ErrInvalidMode, options.Mode)
 		}
 
 	}
 
-	optimal := qr.findOptimalVersion(data)
-	if optimal > 40 {
-		return nil, fmt.Errorf("data too large for a QR Code")
+	e.segments = append(e.segments[:0], Segment{Mode: qr.mode, Data: data})
+	return e.build(qr, e.segments, options, report)
+}
+
+// Validates the error level, FNC1 mode and version given in options.
+func newQRCode(options *Options) (*QRCode, error) {
+	qr := &QRCode{}
+
+	if options == nil {
+		options = &Options{}
 	}
-	if qr.version == 0 {
-		qr.version = optimal
-	} else {
-		if qr.version < 1 || qr.version > 40 {
-			return nil, fmt.Errorf("invalid version number. Must be between 1 and 40")
+
+	qr.errorLevel = "L"
+	if options.Error != "" {
+		if _, err := checkLevel(options.Error); err != nil {
+			return nil, err
 		}
-		if qr.version < optimal {
-			return nil, fmt.Errorf("data too large for version %d", qr.version)
+		qr.errorLevel = options.Error
+	}
+
+	switch options.FNC1 {
+	case 0:
+	case FNC1First:
+		qr.fnc1 = FNC1First
+	case FNC1Second:
+		indicator, err := appIndicator(options.AppIndicator)
+		if err != nil {
+			return nil, err
 		}
+		qr.fnc1 = FNC1Second
+		qr.indicator = indicator
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrInvalidFNC1, options.FNC1)
 	}
 
-	qr.size = qr.version*4 + 17
-	qr.qr = NewBitmap(qr.size, qr.size)
-	qr.mask = NewBitmap(qr.size, qr.size) // Mask for non-functional area of QR Code.
+	qr.version = options.Version
+	for _, version := range []int{options.Version, options.MinVersion, options.MaxVersion} {
+		if version != 0 && (version < 1 || version > 40) {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidVersion, version)
+		}
+	}
+	minVersion, maxVersion := max(options.MinVersion, 1), options.MaxVersion
+	if maxVersion == 0 {
+		maxVersion = 40
+	}
+	if minVersion > maxVersion {
+		return nil, fmt.Errorf("%w: minimum version %d is larger than maximum version %d", ErrInvalidVersion, minVersion, maxVersion)
+	}
+	if qr.version != 0 && (qr.version < minVersion || qr.version > maxVersion) {
+		return nil, fmt.Errorf("%w: %d is not between %d and %d", ErrInvalidVersion, qr.version, minVersion, maxVersion)
+	}
+
+	if options.Mask != nil && (*options.Mask < -1 || *options.Mask > 7) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMask, *options.Mask)
+	}
+
+	return qr, nil
+}
+
+// Builds the QR Code from validated segments.
+func (e *Encoder) build(qr *QRCode, segments []Segment, options *Options, report *Report) (*QRCode, error) {
+	// The largest version the data may use.
+	version := qr.version
+	if version == 0 {
+		version = 40
+		if options.MaxVersion != 0 {
+			version = options.MaxVersion
+		}
+	}
+	optimal := qr.findOptimalVersion(segments)
+	if optimal > version {
+		return nil, qr.tooLarge(version, segments)
+	}
+	if qr.version == 0 {
+		qr.version = max(optimal, options.MinVersion)
+	}
+
+	if options.BoostError {
+		qr.boostErrorLevel(segments)
+	}
 
-	buffer := NewBuffer()
-	// Add data. First add the mode indicator, then the data length, followed by the data.
-	buffer.Add(qr.mode, 4)
-	buffer.Add(len(data), length(qr.version, qr.mode))
-	qr.encode(buffer, data)
+	if report != nil {
+		report.addVersion(qr, segments)
+	}
+
+	qr.size = qr.version*4 + 17
+	e.symbol.reset(qr.size, qr.size)
+	e.function.reset(qr.size, qr.size) // Mask for non-functional area of QR Code.
+	qr.qr = e.symbol
+	qr.mask = e.function
+
+	buffer := e.buffer
+	buffer.Reset()
+	for i, segment := range segments {
+		// The FNC1 mode indicator follows the ECI header of the first segment.
+		addECI(buffer, segment.ECI)
+		if i == 0 {
+			qr.addFNC1(buffer)
+		}
+		// Add data. First add the mode indicator, then the data length, followed by the data.
+		buffer.AppendBits(segment.Mode, 4)
+		buffer.AppendBits(len(segment.Data), length(qr.version, segment.Mode))
+		encodeData(buffer, segment.Mode, segment.Data)
+	}
 
 	index := (qr.version-1)*4 + strings.Index("LMQH", qr.errorLevel)
 
+	dataSize := buffer.Len()
+
 	// Add Termination bits.
-	buffer.Add(0, min(4, capacity[index]-buffer.Size()))
+	buffer.AppendBits(0, min(4, capacity[index]-buffer.Len()))
+	terminator := buffer.Len() - dataSize
 
 	// Add remainder bits to make sure number of bits is a multiple of 8.
-	buffer.Add(0, (8-buffer.Size()%8)%8)
+	buffer.AppendBits(0, (8-buffer.Len()%8)%8)
 
 	// Add alternating padding bits to fill message to full capacity.
-	remaining := (capacity[index] - buffer.Size()) / 8
+	remaining := (capacity[index] - buffer.Len()) / 8
+	if report != nil {
+		report.DataBits = dataSize
+		report.TerminatorBits = terminator
+		report.AlignmentBits = buffer.Len() - dataSize - terminator
+		report.PadBytes = remaining
+	}
 	for i := 0; i < remaining; i++ {
 		if i%2 == 0 {
-			buffer.Add(0b11101100, 8)
+			buffer.AppendBits(0b11101100, 8)
 		} else {
-			buffer.Add(0b00010001, 8)
+			buffer.AppendBits(0b00010001, 8)
 		}
 	}
 
@@ -133,49 +334,67 @@ func NewQRCode(data string, options *Options) (*QRCode, error) {
 		dbSize += blockData[3]
 	}
 
-	bytes := buffer.Bytes()
+	bytes := buffer.data
 
-	dataBlocks := make([][]byte, dbSize)
+	dataBlocks := e.dataBlocks[:0]
 	// After the data has been encoded into a stream of bytes, the stream must
 	// be split into the correct number of blocks as determined by the number
 	// of error correction blocks given by "blockData".
 	current := 0
 	for i := 0; i < dbSize; i++ {
 		if i < blockData[0] {
-			dataBlocks[i] = bytes[current : current+blockData[2]]
+			dataBlocks = append(dataBlocks, bytes[current:current+blockData[2]])
 			current += blockData[2]
 		} else {
-			dataBlocks[i] = bytes[current : current+blockData[5]]
+			dataBlocks = append(dataBlocks, bytes[current:current+blockData[5]])
 			current += blockData[5]
 		}
 	}
+	e.dataBlocks = dataBlocks
 
-	errorBlocks := make([][]byte, len(dataBlocks))
-	for i, block := range dataBlocks {
-		errorBlocks[i] = qr.encodeError(block, i)
-	}
-
-	buffer.Clear()
 	largestBlock := blockData[2]
 	if len(blockData) > 3 {
 		largestBlock = max(largestBlock, blockData[5])
 	}
 	errorwords := blockData[1] - blockData[2]
+
+	// Each block is followed by its error correction codewords in "ecc".
+	stride := largestBlock + errorwords
+	if cap(e.ecc) < dbSize*stride {
+		e.ecc = make([]byte, dbSize*stride)
+	}
+	errorBlocks := e.errorBlocks[:0]
+	for i, block := range dataBlocks {
+		errorBlocks = append(errorBlocks, encodeError(e.ecc[i*stride:(i+1)*stride], block, errorwords))
+	}
+	e.errorBlocks = errorBlocks
+
+	if report != nil {
+		for i := range dataBlocks {
+			report.Blocks = append(report.Blocks, BlockReport{
+				Data:            append(Codewords{}, dataBlocks[i]...),
+				ErrorCorrection: append(Codewords{}, errorBlocks[i]...),
+			})
+		}
+	}
+
+	stream := e.stream
+	stream.Reset()
 	// Interleave data blocks:
 	// Codeword #1 from block #1, codeword #1 from block #2, ..., codeword #1 from block #n
 	// followed by codeword #2 from block #1, codeword #2 from block #2, ..., codeword #2 from block #n
 	// ...
-	for i := 0; i < largestBlock+errorwords; i++ {
+	for i := 0; i < largestBlock; i++ {
 		for _, block := range dataBlocks {
 			if i < len(block) {
-				buffer.Add(int(block[i]), 8)
+				stream.AppendBits(int(block[i]), 8)
 			}
 		}
 	}
 	// Interleave error blocks in the same way as data blocks.
 	for i := 0; i < errorwords; i++ {
 		for _, block := range errorBlocks {
-			buffer.Add(int(block[i]), 8)
+			stream.AppendBits(int(block[i]), 8)
 		}
 	}
 
@@ -187,24 +406,42 @@ func NewQRCode(data string, options *Options) (*QRCode, error) {
 
 	qr.mask.Invert()
 
-	bitstring := buffer.String()
-	mask := qr.findBestMaskPattern(bitstring)
-	qr.addFormatInformation(mask)
+	if report != nil {
+		report.Interleaved = stream.Bytes()
+	}
+
+	mask := -1
+	if options.Mask != nil {
+		mask = *options.Mask
+	}
+	// The report holds the penalties of all masks, even if one was given.
+	if mask < 0 || report != nil {
+		best := e.findBestMaskPattern(qr, stream, report)
+		if mask < 0 {
+			mask = best
+		}
+	}
+	qr.pattern = mask
+	qr.addFormatInformation(qr.qr, mask)
 
-	qr.placeBits(qr.qr, bitstring, mask)
+	qr.placeBits(qr.qr, stream, mask)
 
 	// Add Quiet Zone around the QR Code.
-	qrcode := NewBitmap(qr.size+8, qr.size+8)
-	qrcode.Place(4, 4, qr.qr)
+	qrcode := NewBitmap(qr.size+2*QuietZone, qr.size+2*QuietZone)
+	qrcode.Place(QuietZone, QuietZone, qr.qr)
 	qr.qr = qrcode
+	qr.mask = nil // Owned by the Encoder.
+
+	if options.Verify {
+		if err := qr.verify(segments); err != nil {
+			return nil, err
+		}
+	}
 
 	return qr, nil
 }
 
-func (qr *QRCode) findOptimalVersion(data string) int {
-	buffer := NewBuffer()
-	qr.encode(buffer, data)
-
+func (qr *QRCode) findOptimalVersion(segments []Segment) int {
 	errorIndex := strings.Index("LMQH", qr.errorLevel)
 
 	for version := 1; version <= 40; version++ {
@@ -215,7 +452,7 @@ func (qr *QRCode) findOptimalVersion(data string) int {
 			maxbytes += blockData[3] * blockData[5]
 		}
 
-		size := 4 + length(version, qr.mode) + buffer.Size()
+		size := qr.requiredBits(version, segments)
 		size += max(min(4, capacity[index]-size), 0)
 		size += (8 - size%8) % 8
 
@@ -227,48 +464,97 @@ func (qr *QRCode) findOptimalVersion(data string) int {
 	return 42 // :D
 }
 
-func (qr *QRCode) encode(buffer *Buffer, data string) {
-	switch qr.mode {
+// Raises the error level to the highest level the segments fit in without
+// growing the version.
+func (qr *QRCode) boostErrorLevel(segments []Segment) {
+	level := qr.errorLevel
+	for i := 3; i > strings.Index("LMQH", level); i-- {
+		qr.errorLevel = "LMQH"[i : i+1]
+		if qr.findOptimalVersion(segments) <= qr.version {
+			return
+		}
+	}
+	qr.errorLevel = level
+}
+
+// Returns the number of bits needed for the segments in the given version,
+// without termination and padding.
+func (qr *QRCode) requiredBits(version int, segments []Segment) int {
+	bits := qr.fnc1Size()
+	for _, segment := range segments {
+		bits += segment.size(version)
+	}
+	return bits
+}
+
+func (qr *QRCode) tooLarge(version int, segments []Segment) error {
+	return &DataTooLargeError{
+		Bits:    qr.requiredBits(version, segments),
+		MaxBits: capacity[(version-1)*4+strings.Index("LMQH", qr.errorLevel)],
+		Version: version,
+		Level:   qr.errorLevel,
+	}
+}
+
+// Returns the number of bits needed to encode n characters in the given mode.
+func encodedSize(mode, n int) int {
+	switch mode {
 	case Numeric:
+		return n/3*10 + []int{0, 4, 7}[n%3]
+	case AlphaNum:
+		return n/2*11 + n%2*6
+	}
+	return n * 8
+}
+
+// Adds the FNC1 mode indicator, which precedes all data segments.
+func (qr *QRCode) addFNC1(buffer *Buffer) {
+	switch qr.fnc1 {
+	case FNC1First:
+		buffer.AppendBits(FNC1First, 4)
+	case FNC1Second:
+		buffer.AppendBits(FNC1Second, 4)
+		buffer.AppendBits(qr.indicator, 8)
+	}
+}
+
+func (qr *QRCode) fnc1Size() int {
+	switch qr.fnc1 {
+	case FNC1First:
+		return 4
+	case FNC1Second:
+		return 12
+	}
+	return 0
+}
+
+func encodeData(buffer *Buffer, mode int, data string) {
+	switch mode {
+	case Numeric:
+		// Groups of 3 digits are encoded in 10 bits. A remaining group of 1 or
+		// 2 digits is encoded in 4 or 7 bits respectively.
 		for i := 0; i < len(data); i += 3 {
-			// str = data[i:i+3]
-			str := string(data[i])
-			j := i + 1
-			for j < len(data) && j < i+3 {
-				str += string(data[j])
-				j++
-			}
-			var dlen int
-			switch len(str) {
-			case 1:
-				dlen = 4
-			case 2:
-				dlen = 7
-			case 3:
-				dlen = 10
+			n, digits := 0, 0
+			for j := i; j < len(data) && j < i+3; j++ {
+				n = n*10 + int(data[j]-'0')
+				digits++
 			}
-			n, _ := strconv.Atoi(str)
-			buffer.Add(n, dlen)
+			buffer.AppendBits(n, []int{0, 4, 7, 10}[digits])
 		}
 	case AlphaNum:
-		chars := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+		chars := alphanumChars
 		for i := 0; i < len(data); i += 2 {
-			// str = data[i:i+2]
-			str := string(data[i])
 			if i+1 < len(data) {
-				str += string(data[i+1])
-			}
-			if len(str) > 1 {
-				val := strings.Index(chars, string(str[0])) * 45
-				val += strings.Index(chars, string(str[1]))
-				buffer.Add(val, 11)
+				val := strings.IndexByte(chars, data[i]) * 45
+				val += strings.IndexByte(chars, data[i+1])
+				buffer.AppendBits(val, 11)
 			} else {
-				buffer.Add(strings.Index(chars, string(str[0])), 6)
+				buffer.AppendBits(strings.IndexByte(chars, data[i]), 6)
 			}
 		}
 	case Byte:
 		for _, b := range []byte(data) {
-			buffer.Add(int(b), 8)
+			buffer.AppendBits(int(b), 8)
 		}
 	}
 }
@@ -352,7 +638,7 @@ func (qr *QRCode) addVersionInformation() {
 	}
 }
 
-func (qr *QRCode) addFormatInformation(mask int) {
+func (qr *QRCode) addFormatInformation(bitmap *Bitmap, mask int) {
 	err := strings.Index("MLHQ", qr.errorLevel)
 	format := formatBits[mask|(err<<3)]
 
@@ -362,7 +648,7 @@ func (qr *QRCode) addFormatInformation(mask int) {
 		if y == 6 {
 			continue
 		}
-		qr.qr.Set(8, y, format&(1<<index) != 0)
+		bitmap.Set(8, y, format&(1<<index) != 0)
 		index++
 	}
 	// Format Information to the bottom of the top left position pattern.
@@ -370,45 +656,39 @@ func (qr *QRCode) addFormatInformation(mask int) {
 		if x == 6 {
 			continue
 		}
-		qr.qr.Set(x, 8, format&(1<<index) != 0)
+		bitmap.Set(x, 8, format&(1<<index) != 0)
 		index++
 	}
 	index = 0
 	// Format Information to the bottom of the top right position pattern.
 	for x := qr.size - 1; x >= qr.size-8; x-- {
-		qr.qr.Set(x, 8, format&(1<<index) != 0)
+		bitmap.Set(x, 8, format&(1<<index) != 0)
 		index++
 	}
 	// Format Information to the right of the bottom left position pattern.
 	for y := qr.size - 7; y < qr.size; y++ {
-		qr.qr.Set(8, y, format&(1<<index) != 0)
+		bitmap.Set(8, y, format&(1<<index) != 0)
 		index++
 	}
 
 	// Dark Module
-	qr.qr.Set(8, qr.size-8, true)
+	bitmap.Set(8, qr.size-8, true)
 }
 
 // Employs the Reed-Solomon Algorithm to generate the error correction codewords
 // for a given block of data codewords.
 // See: https://www.matchadesign.com/news/blog/qr-code-demystified-part-4/
 // for an in-depth explanation.
-func (qr *QRCode) encodeError(block []byte, index int) []byte {
-	blockData := blocks[(qr.version-1)*4+strings.Index("LMQH", qr.errorLevel)]
-	codewords, datawords := blockData[1], blockData[2]
-	errorwords := codewords - datawords
-
-	codewordsPerBlock := blockData[2]
-	if index >= blockData[0] {
-		codewordsPerBlock = blockData[5]
-	}
-
-	rserror := make([]byte, len(block)+errorwords)
+func encodeError(dst, block []byte, errorwords int) []byte {
+	rserror := dst[:len(block)+errorwords]
 	copy(rserror, block)
+	for i := len(block); i < len(rserror); i++ {
+		rserror[i] = 0
+	}
 
 	generator := polynomials[errorwords]
 
-	for i := 0; i < codewordsPerBlock; i++ {
+	for i := 0; i < len(block); i++ {
 		coefficient := rserror[0]
 		rserror = rserror[1:]
 
@@ -427,22 +707,40 @@ func (qr *QRCode) encodeError(block []byte, index int) []byte {
 		}
 	}
 
-	if len(rserror) < codewordsPerBlock {
-		rserror = append(rserror, make([]byte, codewordsPerBlock-len(rserror))...)
-	}
-
 	return rserror
 }
 
 // Places the data bitstream into the QR Code represented by a bitmap.
-func (qr *QRCode) placeBits(bitmap *Bitmap, bitstream string, mask int) {
-	inc := -1
-	row := qr.size - 1
+func (qr *QRCode) placeBits(bitmap *Bitmap, bitstream *Buffer, mask int) {
 	index := 0
 
+	// A negative mask places the bits without masking them.
 	mask_func := maskPattern(mask)
 
-	for c := qr.size - 1; c > 0; c -= 2 {
+	walkData(qr.size, qr.mask, func(x, y int) {
+		dark := false
+
+		if index < bitstream.Len() {
+			dark = bitstream.At(index)
+			index++
+		}
+
+		if mask_func != nil && mask_func(x, y) {
+			dark = !dark
+		}
+
+		bitmap.Set(x, y, dark)
+	})
+}
+
+// Visits the modules of the data region in the order the data bits are placed:
+// Upwards and downwards in two module wide columns, starting at the bottom
+// right corner and skipping the vertical timing pattern.
+func walkData(size int, data *Bitmap, visit func(x, y int)) {
+	inc := -1
+	row := size - 1
+
+	for c := size - 1; c > 0; c -= 2 {
 		col := c
 		if col <= 6 {
 			col -= 1
@@ -450,25 +748,14 @@ func (qr *QRCode) placeBits(bitmap *Bitmap, bitstream string, mask int) {
 
 		for {
 			for i := col; i > col-2; i-- {
-				if qr.mask.At(i, row) {
-					dark := false
-
-					if index < len(bitstream) {
-						dark = bitstream[index] == '1'
-						index++
-					}
-
-					if mask_func(i, row) {
-						dark = !dark
-					}
-
-					bitmap.Set(i, row, dark)
+				if data.At(i, row) {
+					visit(i, row)
 				}
 			}
 
 			row += inc
 
-			if row < 0 || qr.size <= row {
+			if row < 0 || size <= row {
 				row -= inc
 				inc = -inc
 				break
@@ -476,121 +763,3 @@ func (qr *QRCode) placeBits(bitmap *Bitmap, bitstream string, mask int) {
 		}
 	}
 }
-
-func (qr *QRCode) findBestMaskPattern(bitstream string) int {
-	bestMask, bestScore := 0, 0
-
-	for mask := 0; mask < 8; mask++ {
-		template := qr.qr.Copy()
-		qr.placeBits(template, bitstream, mask)
-		score := qr.scoreMaskPattern(template)
-
-		if mask == 0 || score < bestScore {
-			bestScore = score
-			bestMask = mask
-		}
-	}
-
-	return bestMask
-}
-
-func (qr *QRCode) scoreMaskPattern(bitmap *Bitmap) int {
-	score := 0
-
-	// Error #1
-	// Five consecutive modules of the same color.
-	prev := bitmap.At(0, 0)
-	for y := 0; y < qr.size; y++ {
-		count := 0
-		for x := 1; x < qr.size; x++ {
-			count = 1
-			curr := bitmap.At(x, y)
-			for prev == curr && x < qr.size-1 {
-				x++
-				count++
-				curr = bitmap.At(x, y)
-			}
-			if count >= 5 {
-				score += (count - 5) + 3
-			}
-			prev = curr
-		}
-		if count >= 5 {
-			score += (count - 5) + 3
-		}
-	}
-	prev = bitmap.At(0, 0)
-	for x := 0; x < qr.size; x++ {
-		count := 0
-		for y := 1; y < qr.size; y++ {
-			count = 1
-			curr := bitmap.At(x, y)
-			for prev == curr && y < qr.size-1 {
-				y++
-				count++
-				curr = bitmap.At(x, y)
-			}
-			if count >= 5 {
-				score += (count - 5) + 3
-			}
-			prev = curr
-		}
-		if count >= 5 {
-			score += (count - 5) + 3
-		}
-	}
-
-	// Error #2
-	// 2x2 blocks of the same color.
-	for y := 0; y < qr.size-1; y++ {
-		for x := 0; x < qr.size-1; x++ {
-			curr := bitmap.At(x, y)
-			if curr == bitmap.At(x-1, y) && curr == bitmap.At(x+1, y) && curr == bitmap.At(x+1, y+1) {
-				score += 3 // Each 2x2 square adds 3 to the score.
-			}
-		}
-	}
-
-	// Error #3
-	// 1011101 Pattern occurences since these conflict with the position patterns.
-	for y := 0; y < qr.size-7; y++ {
-		for x := 0; x < qr.size-7; x++ {
-			pattern := 0b1011101
-			checkRows := 0
-			checkCols := 0
-			for i := 0; i < 7; i++ {
-				// Check along rows.
-				if bitmap.At(x+i, y) == (pattern&(1<<i) != 0) {
-					checkRows++
-				}
-				// Check along columns.
-				if bitmap.At(x, y+i) == (pattern&(1<<i) != 0) {
-					checkCols++
-				}
-			}
-			if checkRows == 7 {
-				score += 40 // Each 1011101 pattern adds 40 to the score.
-			}
-			if checkCols == 7 {
-				score += 40
-			}
-		}
-	}
-
-	// Error #4
-	// Deviation from being 50% dark modules.
-	darkCount := 0
-	for y := 0; y < qr.size; y++ {
-		for x := 0; x < qr.size; x++ {
-			if bitmap.At(x, y) {
-				darkCount++
-			}
-		}
-	}
-
-	ratio := float64(darkCount) / float64(qr.size*qr.size)
-	ratio = ratio*100 - 50
-	score += int((float64(abs(int(ratio))) / 5) * 10)
-
-	return score
-}
@@ -0,0 +1,229 @@
+// Package qrhttp serves QR Codes over HTTP.
+//
+//	http.Handle("/qr", &qrhttp.Handler{MaxDataSize: 1024})
+//
+// The parameters are read from the query or a form encoded POST body:
+//
+//	data     The data to encode (required).
+//	fmt      png (default), jpg or svg.
+//	size     Size of a module in pixels.
+//	level    Error correction level: L, M, Q or H.
+//	version  Version between 1 and 40.
+//	mode     numeric, alphanumeric or byte.
+//	mask     Mask pattern between 0 and 7.
+//	quiet    Width of the quiet zone in modules.
+//	fg, bg   Colors of dark and light modules as #rgb, #rrggbb or #rrggbbaa.
+package qrhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexEidt/qr"
+)
+
+// Default limits of a Handler.
+const (
+	DefaultMaxDataSize = 2048
+	DefaultMaxScale    = 32
+	DefaultScale       = 8
+	DefaultMaxAge      = 24 * time.Hour
+)
+
+const maxQuietZone = 16
+
+// Version of the images served for the same parameters, part of the ETag.
+// Increase it whenever a change to the handler or the library changes the
+// output, so caches do not keep serving the old images.
+const renderVersion = 2
+
+// Handler serves QR Codes built from the request parameters. The zero value
+// uses the default limits.
+type Handler struct {
+	MaxDataSize int           // Maximum size of the data in bytes.
+	MaxScale    int           // Maximum size of a module in pixels.
+	Scale       int           // Size of a module if none is requested.
+	MaxAge      time.Duration // Cache-Control max-age of responses.
+}
+
+// A parsed request.
+type request struct {
+	data    string
+	format  string
+	scale   int
+	quiet   int
+	fg, bg  color.Color
+	options qr.Options
+	mask    int
+}
+
+var contentTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"svg":  "image/svg+xml",
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxDataSize := h.MaxDataSize
+	if maxDataSize <= 0 {
+		maxDataSize = DefaultMaxDataSize
+	}
+	// The form holds the data and a few short parameters.
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxDataSize)*3+1024)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid parameters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req, err := h.parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.data) > maxDataSize {
+		http.Error(w, fmt.Sprintf("data is larger than %d bytes", maxDataSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The output only depends on the parsed parameters.
+	etag := req.etag()
+	maxAge := h.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if match := r.Header.Get("If-None-Match"); match != "" && (match == "*" || strings.Contains(match, etag)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	code, err := qr.NewQRCode(req.data, &req.options)
+	if err != nil {
+		header.Del("ETag")
+		header.Del("Cache-Control")
+		status := http.StatusBadRequest
+		var tooLarge *qr.DataTooLargeError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	bitmap := code.BitmapQuietZone(req.quiet)
+
+	header.Set("Content-Type", contentTypes[req.format])
+	header.Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+	// Errors can not be reported once the image is being written.
+	bitmap.Write(w, req.format, req.scale, req.fg, req.bg)
+}
+
+// Parses and validates the request parameters.
+func (h *Handler) parse(r *http.Request) (*request, error) {
+	form := r.Form
+	req := &request{
+		data:   form.Get("data"),
+		format: strings.ToLower(form.Get("fmt")),
+		scale:  h.Scale,
+		quiet:  qr.QuietZone,
+		mask:   -1,
+	}
+	if _, ok := form["data"]; !ok {
+		return nil, fmt.Errorf("missing data parameter")
+	}
+	if req.format == "" {
+		req.format = "png"
+	}
+	if _, ok := contentTypes[req.format]; !ok {
+		return nil, fmt.Errorf("unsupported format: %q", req.format)
+	}
+	if req.scale <= 0 {
+		req.scale = DefaultScale
+	}
+
+	maxScale := h.MaxScale
+	if maxScale <= 0 {
+		maxScale = DefaultMaxScale
+	}
+	integers := []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"size", &req.scale, 1, maxScale},
+		{"quiet", &req.quiet, 0, maxQuietZone},
+		{"version", &req.options.Version, 1, 40},
+		{"mask", &req.mask, -1, 7},
+	}
+	for _, integer := range integers {
+		s := form.Get(integer.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < integer.min || n > integer.max {
+			return nil, fmt.Errorf("invalid %s: %q. Must be between %d and %d", integer.name, s, integer.min, integer.max)
+		}
+		*integer.value = n
+	}
+	req.options.Mask = &req.mask
+
+	req.options.Error = strings.ToUpper(form.Get("level"))
+	switch strings.ToLower(form.Get("mode")) {
+	case "":
+	case "numeric":
+		req.options.Mode = qr.Numeric
+	case "alphanumeric", "alphanum":
+		req.options.Mode = qr.AlphaNum
+	case "byte", "binary":
+		req.options.Mode = qr.Byte
+	default:
+		return nil, fmt.Errorf("unsupported mode: %q", form.Get("mode"))
+	}
+
+	for _, c := range []struct {
+		name  string
+		value *color.Color
+	}{{"fg", &req.fg}, {"bg", &req.bg}} {
+		if s := form.Get(c.name); s != "" {
+			parsed, err := qr.ParseColor(s)
+			if err != nil {
+				return nil, err
+			}
+			*c.value = parsed
+		}
+	}
+
+	return req, nil
+}
+
+// Returns a strong ETag of the parameters that determine the output and the
+// render version.
+func (req *request) etag() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d %q %s %d %d %v %v %d %d %s %d", renderVersion,
+		req.data, req.format, req.scale, req.quiet, req.fg, req.bg,
+		req.options.Version, req.options.Mode, req.options.Error, req.mask)
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
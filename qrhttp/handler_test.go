@@ -0,0 +1,110 @@
+package qrhttp
+
+import (
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexEidt/qr"
+)
+
+func assertEquals(actual, expected interface{}) {
+	if actual != expected {
+		panic(fmt.Sprintf("Expected %v, got %v", expected, actual))
+	}
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestPNG(t *testing.T) {
+	handler := &Handler{}
+	w := serve(handler, httptest.NewRequest("GET", "/qr?data=HELLO&size=3&quiet=1&level=h", nil))
+	assertEquals(w.Code, http.StatusOK)
+	assertEquals(w.Header().Get("Content-Type"), "image/png")
+	assertEquals(w.Header().Get("Cache-Control"), "public, max-age=86400")
+
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		panic(err)
+	}
+	assertEquals(img.Bounds().Dx(), (21+2)*3)
+
+	code, err := qr.NewQRCode("HELLO", &qr.Options{Error: "H"})
+	if err != nil {
+		panic(err)
+	}
+	bitmap := qr.NewBitmapFromImage(img)
+	assertEquals(bitmap.Equal(code.Bitmap().Crop(3, 3, 23, 23).Scale(3)), true)
+
+	// The same parameters give the same ETag, which is answered with 304.
+	etag := w.Header().Get("ETag")
+	assertEquals(serve(handler, httptest.NewRequest("GET", "/qr?level=h&quiet=1&size=3&data=HELLO", nil)).Header().Get("ETag"), etag)
+	assertEquals(serve(handler, httptest.NewRequest("GET", "/qr?data=HELLO&size=4", nil)).Header().Get("ETag") != etag, true)
+
+	r := httptest.NewRequest("GET", "/qr?data=HELLO&size=3&quiet=1&level=H", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = serve(handler, r)
+	assertEquals(w.Code, http.StatusNotModified)
+	assertEquals(w.Body.Len(), 0)
+}
+
+func TestFormats(t *testing.T) {
+	handler := &Handler{MaxAge: time.Minute}
+	form := url.Values{"data": {"https://example.com"}, "fmt": {"svg"}, "fg": {"#123"}}
+	r := httptest.NewRequest("POST", "/qr", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := serve(handler, r)
+	assertEquals(w.Code, http.StatusOK)
+	assertEquals(w.Header().Get("Content-Type"), "image/svg+xml")
+	assertEquals(w.Header().Get("Cache-Control"), "public, max-age=60")
+	assertEquals(strings.HasPrefix(w.Body.String(), "<svg"), true)
+	assertEquals(strings.Contains(w.Body.String(), `fill="#112233"`), true)
+
+	w = serve(handler, httptest.NewRequest("GET", "/qr?data=1&fmt=jpg", nil))
+	assertEquals(w.Header().Get("Content-Type"), "image/jpeg")
+
+	w = serve(handler, httptest.NewRequest("HEAD", "/qr?data=1", nil))
+	assertEquals(w.Code, http.StatusOK)
+	assertEquals(w.Body.Len(), 0)
+}
+
+func TestLimits(t *testing.T) {
+	handler := &Handler{MaxDataSize: 10, MaxScale: 5}
+
+	for _, test := range []struct {
+		target string
+		status int
+	}{
+		{"/qr", http.StatusBadRequest},
+		{"/qr?data=HELLO&size=6", http.StatusBadRequest},
+		{"/qr?data=HELLO&size=0", http.StatusBadRequest},
+		{"/qr?data=HELLO&quiet=17", http.StatusBadRequest},
+		{"/qr?data=HELLO&fmt=gif", http.StatusBadRequest},
+		{"/qr?data=HELLO&level=X", http.StatusBadRequest},
+		{"/qr?data=HELLO&mode=numeric", http.StatusBadRequest},
+		{"/qr?data=HELLO&fg=blue", http.StatusBadRequest},
+		{"/qr?data=HELLO+WORLD", http.StatusRequestEntityTooLarge},
+		{"/qr?data=1234567&version=1&level=H&mode=byte", http.StatusOK},
+		{"/qr?data=12345678&version=1&level=H&mode=byte", http.StatusRequestEntityTooLarge},
+	} {
+		w := serve(handler, httptest.NewRequest("GET", test.target, nil))
+		assertEquals(fmt.Sprint(test.target, " ", w.Code), fmt.Sprint(test.target, " ", test.status))
+	}
+
+	w := serve(handler, httptest.NewRequest("DELETE", "/qr?data=HELLO", nil))
+	assertEquals(w.Code, http.StatusMethodNotAllowed)
+	assertEquals(w.Header().Get("Allow"), "GET, HEAD, POST")
+
+	r := httptest.NewRequest("POST", "/qr", strings.NewReader("data="+strings.Repeat("A", 2000)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assertEquals(serve(handler, r).Code, http.StatusBadRequest)
+}
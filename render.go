@@ -2,43 +2,26 @@ package qr
 
 import (
 	"fmt"
-	"image"
+	"html"
 	"image/color"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
+// Render writes the QR Code to a file. The format is given by the file
+// extension: .png, .jpg, .jpeg or .svg.
 func (qr *QRCode) Render(filename string, scale int) error {
 	extension := filepath.Ext(filename)
 	switch extension {
-	case ".png", ".jpg", ".jpeg":
-		return qr.renderRaster(filename, scale)
-	case ".svg":
-		return qr.renderVector(filename, scale)
+	case ".png", ".jpg", ".jpeg", ".svg":
+	default:
+		return fmt.Errorf("unsupported file extension: %s", extension)
 	}
-	return fmt.Errorf("unsupported file extension: %s", extension)
-}
-
-func (qr *QRCode) renderVector(filename string, scale int) error {
-	writer := NewBuffer()
-
-	template := `<svg version="1.1" encoding="UTF-8" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`
-	writer.Write(fmt.Sprintf(template, qr.qr.Width()*scale, qr.qr.Height()*scale))
-
-	writer.Write(`<rect width="100%" height="100%" fill="white" />`)
-
-	for h := 0; h < qr.qr.Height(); h++ {
-		for w := 0; w < qr.qr.Width(); w++ {
-			if qr.qr.At(w, h) {
-				template := `<rect x="%d" y="%d" width="%d" height="%d" fill="#000" />`
-				writer.Write(fmt.Sprintf(template, w*scale, h*scale, scale, scale))
-			}
-		}
-	}
-
-	writer.Write("</svg>")
 
 	f, err := os.Create(filename)
 	if err != nil {
@@ -46,52 +29,119 @@ func (qr *QRCode) renderVector(filename string, scale int) error {
 	}
 	defer f.Close()
 
-	_, err = f.Write([]byte(writer.String()))
-	if err != nil {
+	if err := qr.Write(f, extension[1:], scale); err != nil {
 		return err
 	}
 
-	return nil
+	return f.Close()
 }
 
-func (qr *QRCode) renderRaster(filename string, scale int) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+// Write encodes the QR Code to w in the given format: png, jpg, jpeg or svg.
+func (qr *QRCode) Write(w io.Writer, format string, scale int) error {
+	return qr.qr.Write(w, format, scale, nil, nil)
+}
+
+// Write encodes the bitmap to w in the given format: png, jpg, jpeg or svg.
+// Set modules are drawn in fg and unset modules in bg. A nil fg defaults to
+// black and a nil bg to white. A scale below 1 is treated as 1.
+func (b *Bitmap) Write(w io.Writer, format string, scale int, fg, bg color.Color) error {
+	switch format {
+	case "png", "jpg", "jpeg":
+		return b.renderRaster(w, format, scale, fg, bg)
+	case "svg":
+		return b.renderVector(w, scale, fg, bg, svgOptions{})
 	}
-	defer f.Close()
+	return fmt.Errorf("unsupported format: %s", format)
+}
 
+// Accessible text of an SVG image. Empty fields are left out.
+type svgOptions struct {
+	title       string
+	description string
+}
+
+func (b *Bitmap) renderVector(w io.Writer, scale int, fg, bg color.Color, options svgOptions) error {
+	// Like Image, which the raster formats use.
 	if scale < 1 {
 		scale = 1
 	}
 
-	image := image.NewRGBA(image.Rect(0, 0, qr.qr.Width()*scale, qr.qr.Height()*scale))
-	for h := 0; h < qr.qr.Height(); h++ {
-		for w := 0; w < qr.qr.Width(); w++ {
-			var c color.RGBA
-			if qr.qr.At(w, h) {
-				c = color.RGBA{0, 0, 0, 255}
-			} else {
-				c = color.RGBA{255, 255, 255, 255}
-			}
-			for dy := 0; dy < scale; dy++ {
-				for dx := 0; dx < scale; dx++ {
-					image.Set(w*scale+dx, h*scale+dy, c)
-				}
+	var writer strings.Builder
+
+	foreground, background := "#000", "white"
+	if fg != nil {
+		foreground = hexColor(fg)
+	}
+	if bg != nil {
+		background = hexColor(bg)
+	}
+
+	// The viewBox lets the image be scaled with CSS when it is embedded.
+	template := `<svg version="1.1" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d" role="img">`
+	writer.WriteString(fmt.Sprintf(template, b.Width()*scale, b.Height()*scale))
+	if options.title != "" {
+		writer.WriteString("<title>" + html.EscapeString(options.title) + "</title>")
+	}
+	if options.description != "" {
+		writer.WriteString("<desc>" + html.EscapeString(options.description) + "</desc>")
+	}
+
+	writer.WriteString(fmt.Sprintf(`<rect width="100%%" height="100%%" fill="%s" />`, background))
+
+	for h := 0; h < b.Height(); h++ {
+		for w := 0; w < b.Width(); w++ {
+			if b.At(w, h) {
+				template := `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" />`
+				writer.WriteString(fmt.Sprintf(template, w*scale, h*scale, scale, scale, foreground))
 			}
 		}
 	}
 
-	switch filepath.Ext(filename) {
-	case ".png":
-		if err := png.Encode(f, image); err != nil {
+	writer.WriteString("</svg>")
+
+	_, err := io.WriteString(w, writer.String())
+	return err
+}
+
+func (b *Bitmap) renderRaster(w io.Writer, format string, scale int, fg, bg color.Color) error {
+	image := b.Image(scale, fg, bg)
+
+	switch format {
+	case "png":
+		if err := png.Encode(w, image); err != nil {
 			return err
 		}
-	case ".jpg", ".jpeg":
-		if err := jpeg.Encode(f, image, nil); err != nil {
+	case "jpg", "jpeg":
+		if err := jpeg.Encode(w, image, nil); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// ParseColor parses a color given as #rgb, #rrggbb or #rrggbbaa. The leading
+// "#" is optional.
+func ParseColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("invalid color: %q", s)
+	}
+	return color.NRGBA{R: uint8(value >> 24), G: uint8(value >> 16), B: uint8(value >> 8), A: uint8(value)}, nil
+}
+
+// Returns the color as #rrggbb, or #rrggbbaa if it is not opaque.
+func hexColor(c color.Color) string {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if nrgba.A != 0xff {
+		return fmt.Sprintf("#%02x%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B, nrgba.A)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B)
+}
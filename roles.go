@@ -0,0 +1,191 @@
+package qr
+
+import (
+	"strings"
+)
+
+// Role is the purpose of a module in a QR Code.
+type Role uint8
+
+const (
+	RoleQuietZone Role = iota
+	RoleFinder
+	RoleSeparator
+	RoleTiming
+	RoleAlignment
+	RoleFormat
+	RoleVersion
+	RoleDarkModule
+	RoleData
+	RoleErrorCorrection
+	RoleRemainder
+)
+
+var roleNames = []string{
+	"quiet zone",
+	"finder",
+	"separator",
+	"timing",
+	"alignment",
+	"format",
+	"version",
+	"dark module",
+	"data",
+	"error correction",
+	"remainder",
+}
+
+func (r Role) String() string {
+	if int(r) < len(roleNames) {
+		return roleNames[r]
+	}
+	return "unknown"
+}
+
+// ModuleRole describes a single module. For data and error correction
+// modules, Block is the index of the block, Index the index of the codeword
+// within the data or error correction codewords of the block, and Bit the bit
+// of the codeword, with 7 being the most significant bit. For all other
+// modules these fields are -1.
+type ModuleRole struct {
+	Role  Role
+	Block int
+	Index int
+	Bit   int
+}
+
+// Roles returns the role of every module of the QR Code, including the Quiet
+// Zone, indexed as [y][x] in the same coordinates as Bitmap.
+func (qr *QRCode) Roles() [][]ModuleRole {
+	size := qr.size + 2*QuietZone
+	roles := make([][]ModuleRole, size)
+	for y := range roles {
+		roles[y] = make([]ModuleRole, size)
+		for x := range roles[y] {
+			roles[y][x] = ModuleRole{Role: RoleQuietZone, Block: -1, Index: -1, Bit: -1}
+		}
+	}
+
+	functions := functionRoles(qr.version)
+	data := NewBitmap(qr.size, qr.size)
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			if functions[y][x] == RoleData {
+				data.Set(x, y, true)
+			} else {
+				roles[y+QuietZone][x+QuietZone].Role = functions[y][x]
+			}
+		}
+	}
+
+	codewords := qr.codewordOrder()
+	index := 0
+	walkData(qr.size, data, func(x, y int) {
+		module := &roles[y+QuietZone][x+QuietZone]
+		if index/8 < len(codewords) {
+			*module = codewords[index/8]
+			module.Bit = 7 - index%8
+		} else {
+			module.Role = RoleRemainder
+		}
+		index++
+	})
+
+	return roles
+}
+
+// Returns the block and index of each codeword in the interleaved sequence of
+// data and error correction codewords.
+func (qr *QRCode) codewordOrder() []ModuleRole {
+	blockData := blocks[(qr.version-1)*4+strings.Index("LMQH", qr.errorLevel)]
+
+	var sizes []int
+	for i := 0; i < blockData[0]; i++ {
+		sizes = append(sizes, blockData[2])
+	}
+	largestBlock := blockData[2]
+	if len(blockData) > 3 {
+		for i := 0; i < blockData[3]; i++ {
+			sizes = append(sizes, blockData[5])
+		}
+		largestBlock = max(largestBlock, blockData[5])
+	}
+	errorwords := blockData[1] - blockData[2]
+
+	var order []ModuleRole
+	for i := 0; i < largestBlock; i++ {
+		for block, size := range sizes {
+			if i < size {
+				order = append(order, ModuleRole{Role: RoleData, Block: block, Index: i})
+			}
+		}
+	}
+	for i := 0; i < errorwords; i++ {
+		for block := range sizes {
+			order = append(order, ModuleRole{Role: RoleErrorCorrection, Block: block, Index: i})
+		}
+	}
+
+	return order
+}
+
+// Returns the role of each module of a QR Code of the given version without
+// Quiet Zone. Modules of the data region are marked as RoleData.
+func functionRoles(version int) [][]Role {
+	size := version*4 + 17
+	roles := make([][]Role, size)
+	for y := range roles {
+		roles[y] = make([]Role, size)
+		for x := range roles[y] {
+			roles[y][x] = RoleData
+		}
+	}
+	fill := func(x, y, w, h int, role Role) {
+		for i := y; i < y+h; i++ {
+			for j := x; j < x+w; j++ {
+				roles[i][j] = role
+			}
+		}
+	}
+
+	// Timing patterns first, so they are overwritten by the other patterns.
+	fill(6, 0, 1, size, RoleTiming)
+	fill(0, 6, size, 1, RoleTiming)
+
+	// Position patterns and their separators.
+	fill(0, 0, 8, 8, RoleSeparator)
+	fill(size-8, 0, 8, 8, RoleSeparator)
+	fill(0, size-8, 8, 8, RoleSeparator)
+	fill(0, 0, 7, 7, RoleFinder)
+	fill(size-7, 0, 7, 7, RoleFinder)
+	fill(0, size-7, 7, 7, RoleFinder)
+
+	vals := alignmentPositions[version-1]
+	for i := 0; i < len(vals); i++ {
+		for j := 0; j < len(vals); j++ {
+			if (i == 0 && j == 0) || (i == len(vals)-1 && j == 0) || (i == 0 && j == len(vals)-1) {
+				continue
+			}
+			fill(vals[i]-2, vals[j]-2, 5, 5, RoleAlignment)
+		}
+	}
+
+	// Format Information around the top left position pattern, below the top
+	// right and to the right of the bottom left position pattern.
+	for i := 0; i < 9; i++ {
+		if i != 6 {
+			roles[i][8] = RoleFormat
+			roles[8][i] = RoleFormat
+		}
+	}
+	fill(size-8, 8, 8, 1, RoleFormat)
+	fill(8, size-7, 1, 7, RoleFormat)
+	roles[size-8][8] = RoleDarkModule
+
+	if version >= 7 {
+		fill(0, size-11, 6, 3, RoleVersion)
+		fill(size-11, 0, 3, 6, RoleVersion)
+	}
+
+	return roles
+}
@@ -0,0 +1,105 @@
+package qr
+
+import (
+	"fmt"
+)
+
+// Segment is a part of the data encoded in a single mode.
+type Segment struct {
+	Mode int // Numeric, AlphaNum or Byte.
+	Data string
+	// ECI is the Extended Channel Interpretation assignment number placed
+	// before the segment, for example 26 for UTF-8. Zero means no ECI header.
+	ECI int
+}
+
+// ECI mode indicator.
+const eciMode = 7
+
+// NewQRCodeFromSegments builds a QR Code from segments that are encoded in
+// the given order, for example a serial number in Numeric mode followed by a
+// URL in Byte mode. The Mode of options is ignored.
+func NewQRCodeFromSegments(segments []Segment, options *Options) (*QRCode, error) {
+	encoder := encoders.Get().(*Encoder)
+	defer encoders.Put(encoder)
+	return encoder.EncodeSegments(segments, options)
+}
+
+// EncodeSegments builds a QR Code like NewQRCodeFromSegments.
+func (e *Encoder) EncodeSegments(segments []Segment, options *Options) (*QRCode, error) {
+	qr, err := newQRCode(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no segments given")
+	}
+	if options == nil {
+		options = &Options{}
+	}
+
+	e.segments = e.segments[:0]
+	offset := 0
+	for _, segment := range segments {
+		switch segment.Mode {
+		case Numeric, AlphaNum, Byte:
+		default:
+			return nil, fmt.Errorf("%w: %d", ErrInvalidMode, segment.Mode)
+		}
+		if segment.ECI < 0 || segment.ECI > 999999 {
+			return nil, fmt.Errorf("invalid ECI assignment number: %d. Must be between 0 and 999999", segment.ECI)
+		}
+		if i := modeOffset(segment.Data, segment.Mode, qr.fnc1 != 0); i >= 0 {
+			required := Byte
+			if modeOffset(segment.Data, AlphaNum, qr.fnc1 != 0) < 0 {
+				required = AlphaNum
+			}
+			return nil, &ModeMismatchError{Requested: segment.Mode, Required: required, Offset: offset + i}
+		}
+		offset += len(segment.Data)
+
+		if qr.fnc1 != 0 && segment.Mode == AlphaNum {
+			segment.Data = escapeFNC1(segment.Data)
+		}
+		qr.mode = max(qr.mode, segment.Mode)
+		e.segments = append(e.segments, segment)
+	}
+
+	return e.build(qr, e.segments, options, nil)
+}
+
+// Returns the number of bits of the segment in the given version.
+func (s *Segment) size(version int) int {
+	return eciSize(s.ECI) + 4 + length(version, s.Mode) + encodedSize(s.Mode, len(s.Data))
+}
+
+// Returns the number of bits of the ECI header, including its mode indicator.
+func eciSize(eci int) int {
+	switch {
+	case eci == 0:
+		return 0
+	case eci < 1<<7:
+		return 4 + 8
+	case eci < 1<<14:
+		return 4 + 16
+	}
+	return 4 + 24
+}
+
+// Adds the ECI header. The assignment number is encoded in 1, 2 or 3
+// codewords, whose leading bits give the number of codewords.
+func addECI(buffer *Buffer, eci int) {
+	switch {
+	case eci == 0:
+		return
+	case eci < 1<<7:
+		buffer.AppendBits(eciMode, 4)
+		buffer.AppendBits(eci, 8)
+	case eci < 1<<14:
+		buffer.AppendBits(eciMode, 4)
+		buffer.AppendBits(0b10<<14|eci, 16)
+	default:
+		buffer.AppendBits(eciMode, 4)
+		buffer.AppendBits(0b110<<21|eci, 24)
+	}
+}
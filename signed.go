@@ -0,0 +1,142 @@
+package qr
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// Signer signs payloads with Ed25519 so that printed codes can be verified
+// offline. The signed envelope has the layout
+//
+//	version (1 byte) | key id length (1 byte) | key id | payload | signature (64 bytes)
+//
+// where the signature covers everything before it.
+type Signer struct {
+	KeyID string
+	Key   ed25519.PrivateKey
+	// Base45 encodes the envelope as Base45 so it is stored in Alphanumeric
+	// mode instead of Byte mode. This usually results in a smaller symbol.
+	Base45 bool
+}
+
+const envelopeVersion = 1
+
+// Sign returns the signed envelope for the payload.
+func (s *Signer) Sign(payload []byte) ([]byte, error) {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key size: %d", len(s.Key))
+	}
+	envelope, err := s.envelope(payload)
+	if err != nil {
+		return nil, err
+	}
+	return append(envelope, ed25519.Sign(s.Key, envelope)...), nil
+}
+
+func (s *Signer) envelope(payload []byte) ([]byte, error) {
+	if len(s.KeyID) > 255 {
+		return nil, fmt.Errorf("key id must be at most 255 bytes long")
+	}
+	envelope := make([]byte, 0, 2+len(s.KeyID)+len(payload)+ed25519.SignatureSize)
+	envelope = append(envelope, envelopeVersion, byte(len(s.KeyID)))
+	envelope = append(envelope, s.KeyID...)
+	return append(envelope, payload...), nil
+}
+
+// Payload returns the signed envelope as the text to encode in a QR Code.
+func (s *Signer) Payload(payload []byte) (string, error) {
+	envelope, err := s.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	if s.Base45 {
+		return Base45Encode(envelope), nil
+	}
+	return string(envelope), nil
+}
+
+func (s *Signer) mode() int {
+	if s.Base45 {
+		return AlphaNum
+	}
+	return Byte
+}
+
+// QRCode builds a QR Code containing the signed payload. Any Mode in options
+// is overridden.
+func (s *Signer) QRCode(payload []byte, options *Options) (*QRCode, error) {
+	data, err := s.Payload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := copyOptions(options)
+	opts.Mode = s.mode()
+
+	return NewQRCode(data, &opts)
+}
+
+// Versions returns the smallest QR Code version that fits the signed payload
+// for each error correction level, or 0 if it does not fit in a QR Code.
+// Signatures have a fixed size, so the payload does not have to be signed.
+func (s *Signer) Versions(payload []byte) (map[string]int, error) {
+	envelope, err := s.envelope(payload)
+	if err != nil {
+		return nil, err
+	}
+	envelope = append(envelope, make([]byte, ed25519.SignatureSize)...)
+
+	data := string(envelope)
+	if s.Base45 {
+		data = Base45Encode(envelope)
+	}
+
+	versions := map[string]int{}
+	for _, level := range "LMQH" {
+		qr := &QRCode{mode: s.mode(), errorLevel: string(level)}
+		versions[string(level)] = qr.findOptimalVersion([]Segment{{Mode: qr.mode, Data: data}})
+		if versions[string(level)] > 40 {
+			versions[string(level)] = 0
+		}
+	}
+
+	return versions, nil
+}
+
+// VerifySigned checks the signature of the scanned text of a signed QR Code against
+// the public key with the matching key id and returns the payload.
+func VerifySigned(data string, keys map[string]ed25519.PublicKey) ([]byte, string, error) {
+	envelope := []byte(data)
+	// Base45 text never starts with the envelope version byte.
+	if len(data) == 0 || data[0] != envelopeVersion {
+		var err error
+		if envelope, err = Base45Decode(data); err != nil {
+			return nil, "", fmt.Errorf("invalid signed payload: %v", err)
+		}
+	}
+
+	if len(envelope) < 2 || envelope[0] != envelopeVersion {
+		return nil, "", fmt.Errorf("unsupported signed payload version")
+	}
+	idEnd := 2 + int(envelope[1])
+	if len(envelope) < idEnd+ed25519.SignatureSize {
+		return nil, "", fmt.Errorf("signed payload is truncated")
+	}
+
+	keyID := string(envelope[2:idEnd])
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, keyID, fmt.Errorf("unknown key id: %q", keyID)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, keyID, fmt.Errorf("invalid ed25519 public key size: %d", len(key))
+	}
+
+	signed := envelope[:len(envelope)-ed25519.SignatureSize]
+	signature := envelope[len(envelope)-ed25519.SignatureSize:]
+	if !ed25519.Verify(key, signed, signature) {
+		return nil, keyID, fmt.Errorf("invalid signature for key id %q", keyID)
+	}
+
+	return signed[idEnd:], keyID, nil
+}
@@ -1,7 +1,8 @@
 package qr
 
 import (
-	"regexp"
+	"fmt"
+	"strings"
 )
 
 func min(a, b int) int {
@@ -26,15 +27,19 @@ func abs(a int) int {
 }
 
 func findMode(data string) int {
-	digits := regexp.MustCompile("^[0-9]+$")
-	if digits.MatchString(data) {
-		return Numeric
+	mode := Numeric
+	for i := 0; i < len(data); i++ {
+		if !isDigit(data[i]) {
+			if strings.IndexByte(alphanumChars, data[i]) < 0 {
+				return Byte
+			}
+			mode = AlphaNum
+		}
 	}
-	alphanum := regexp.MustCompile(`^[0-9A-Z \$\%\*\+\-\.\/\:]+$`)
-	if alphanum.MatchString(data) {
-		return AlphaNum
+	if len(data) == 0 {
+		return Byte
 	}
-	return Byte
+	return mode
 }
 
 func length(version, mode int) int {
@@ -91,3 +96,43 @@ func maskPattern(mask int) func(int, int) bool {
 	}
 	return nil
 }
+
+// Returns the offset of the first character of data that cannot be encoded in
+// the given mode. In FNC1 mode, the Group Separator is encoded as "%" in
+// Alphanumeric mode.
+func modeOffset(data string, mode int, fnc1 bool) int {
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case isDigit(c):
+		case mode == AlphaNum && (strings.IndexByte(alphanumChars, c) >= 0 || (fnc1 && c == 0x1d)):
+		case mode == Byte:
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+func escapeFNC1(data string) string {
+	return strings.NewReplacer("%", "%%", "\x1d", "%").Replace(data)
+}
+
+// Converts an AIM Application Indicator to its codeword value. Letters are
+// encoded as their ASCII value + 100, two digits as their numeric value.
+func appIndicator(indicator string) (int, error) {
+	if len(indicator) == 1 {
+		c := indicator[0]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			return int(c) + 100, nil
+		}
+	}
+	if len(indicator) == 2 && isDigit(indicator[0]) && isDigit(indicator[1]) {
+		return int(indicator[0]-'0')*10 + int(indicator[1]-'0'), nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidAppIndicator, indicator)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,379 @@
+package qr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reads the QR Code back from its bitmap and checks that it holds the given
+// segments. The symbol is read without the tables and helpers used to build
+// it, apart from the block structure and the character count lengths.
+func (qr *QRCode) verify(segments []Segment) error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrVerify}, args...)...)
+	}
+
+	size := qr.qr.width - 2*QuietZone
+	module := func(x, y int) bool {
+		return qr.qr.At(x+QuietZone, y+QuietZone)
+	}
+
+	version := (size - 17) / 4
+	if version != qr.version || size != version*4+17 {
+		return fail("size %d does not match version %d", size, qr.version)
+	}
+
+	// Format Information around the top left position pattern and next to
+	// the other two position patterns.
+	var format [2]int
+	index := 0
+	for y := 0; y < 9; y++ {
+		if y != 6 {
+			format[0] |= bit(module(8, y)) << index
+			index++
+		}
+	}
+	for x := 7; x >= 0; x-- {
+		if x != 6 {
+			format[0] |= bit(module(x, 8)) << index
+			index++
+		}
+	}
+	index = 0
+	for x := size - 1; x >= size-8; x-- {
+		format[1] |= bit(module(x, 8)) << index
+		index++
+	}
+	for y := size - 7; y < size; y++ {
+		format[1] |= bit(module(8, y)) << index
+		index++
+	}
+	if format[0] != format[1] {
+		return fail("format information copies differ")
+	}
+	level, mask := "", -1
+	for i := 0; i < 32; i++ {
+		if (i<<10|bch(i, 10, 0b10100110111))^0b101010000010010 == format[0] {
+			level, mask = "MLHQ"[i>>3:i>>3+1], i&7
+		}
+	}
+	if level != qr.errorLevel || mask != qr.pattern {
+		return fail("format information %015b does not match level %s and mask %d", format[0], qr.errorLevel, qr.pattern)
+	}
+	if !module(8, size-8) {
+		return fail("dark module is missing")
+	}
+
+	if version >= 7 {
+		var info [2]int
+		index = 0
+		for x := 0; x < 6; x++ {
+			for y := size - 11; y < size-8; y++ {
+				info[0] |= bit(module(x, y)) << index
+				info[1] |= bit(module(y, x)) << index
+				index++
+			}
+		}
+		expected := version<<12 | bch(version, 12, 0b1111100100101)
+		if info[0] != expected || info[1] != expected {
+			return fail("version information does not match version %d", version)
+		}
+	}
+
+	// Read the codewords in two module wide columns from the bottom right,
+	// skipping the function patterns and the vertical timing pattern.
+	roles := functionRoles(version)
+	var codewords []byte
+	var current byte
+	bits := 0
+	upwards := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for i := 0; i < size; i++ {
+			y := i
+			if upwards {
+				y = size - 1 - i
+			}
+			for x := right; x > right-2; x-- {
+				if roles[y][x] != RoleData {
+					continue
+				}
+				current = current<<1 | byte(bit(module(x, y) != masked(mask, x, y)))
+				bits++
+				if bits%8 == 0 {
+					codewords = append(codewords, current)
+					current = 0
+				}
+			}
+		}
+		upwards = !upwards
+	}
+
+	// Deinterleave the codewords and check the error correction of each block.
+	blockData := blocks[(version-1)*4+strings.Index("LMQH", level)]
+	sizes := []int{}
+	for i := 0; i < blockData[0]; i++ {
+		sizes = append(sizes, blockData[2])
+	}
+	if len(blockData) > 3 {
+		for i := 0; i < blockData[3]; i++ {
+			sizes = append(sizes, blockData[5])
+		}
+	}
+	errorwords := blockData[1] - blockData[2]
+	total := 0
+	for _, n := range sizes {
+		total += n + errorwords
+	}
+	if len(codewords) < total {
+		return fail("read %d codewords, expected %d", len(codewords), total)
+	}
+
+	blockWords := make([][]byte, len(sizes))
+	index = 0
+	for i := 0; index < total-errorwords*len(sizes); i++ {
+		for block, n := range sizes {
+			if i < n {
+				blockWords[block] = append(blockWords[block], codewords[index])
+				index++
+			}
+		}
+	}
+	for i := 0; i < errorwords; i++ {
+		for block := range sizes {
+			blockWords[block] = append(blockWords[block], codewords[index])
+			index++
+		}
+	}
+
+	data := &Buffer{}
+	for block, words := range blockWords {
+		for i := 0; i < errorwords; i++ {
+			if syndrome(words, i) != 0 {
+				return fail("block %d has a non-zero syndrome", block)
+			}
+		}
+		for _, word := range words[:sizes[block]] {
+			data.AppendBits(int(word), 8)
+		}
+	}
+
+	decoded, fnc1, indicator, err := parseSegments(data, version)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if fnc1 != qr.fnc1 || indicator != qr.indicator {
+		return fail("FNC1 mode %d does not match %d", fnc1, qr.fnc1)
+	}
+	if len(decoded) != len(segments) {
+		return fail("decoded %d segments, expected %d", len(decoded), len(segments))
+	}
+	for i := range segments {
+		if decoded[i] != segments[i] {
+			return fail("segment %d decoded as %q in mode %d, expected %q in mode %d",
+				i, decoded[i].Data, decoded[i].Mode, segments[i].Data, segments[i].Mode)
+		}
+	}
+
+	return nil
+}
+
+// Parses the segments of the data codewords and checks the padding after them.
+func parseSegments(data *Buffer, version int) ([]Segment, int, int, error) {
+	position := 0
+	read := func(n int) (int, error) {
+		if position+n > data.Len() {
+			return 0, fmt.Errorf("bitstream ends at bit %d", data.Len())
+		}
+		value := 0
+		for i := 0; i < n; i++ {
+			value = value<<1 | bit(data.At(position))
+			position++
+		}
+		return value, nil
+	}
+
+	var segments []Segment
+	fnc1, indicator, eci := 0, 0, 0
+	for data.Len()-position >= 4 {
+		mode, _ := read(4)
+		if mode == 0 {
+			break
+		}
+
+		switch mode {
+		case eciMode:
+			first, err := read(8)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			switch {
+			case first>>7 == 0:
+				eci = first
+			case first>>6 == 0b10:
+				rest, err := read(8)
+				if err != nil {
+					return nil, 0, 0, err
+				}
+				eci = (first&0x3f)<<8 | rest
+			default:
+				rest, err := read(16)
+				if err != nil {
+					return nil, 0, 0, err
+				}
+				eci = (first&0x1f)<<16 | rest
+			}
+			continue
+		case FNC1First:
+			fnc1 = FNC1First
+			continue
+		case FNC1Second:
+			fnc1 = FNC1Second
+			value, err := read(8)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			indicator = value
+			continue
+		case Numeric, AlphaNum, Byte:
+		default:
+			return nil, 0, 0, fmt.Errorf("unknown mode indicator %04b", mode)
+		}
+
+		count, err := read(length(version, mode))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		var builder strings.Builder
+		for count > 0 {
+			switch mode {
+			case Numeric:
+				digits := min(count, 3)
+				value, err := read([]int{0, 4, 7, 10}[digits])
+				if err != nil {
+					return nil, 0, 0, err
+				}
+				builder.WriteString(fmt.Sprintf("%0*d", digits, value))
+				count -= digits
+			case AlphaNum:
+				if count >= 2 {
+					value, err := read(11)
+					if err != nil {
+						return nil, 0, 0, err
+					}
+					if value >= 45*45 {
+						return nil, 0, 0, fmt.Errorf("invalid Alphanumeric value %d", value)
+					}
+					builder.WriteByte(alphanumChars[value/45])
+					builder.WriteByte(alphanumChars[value%45])
+					count -= 2
+				} else {
+					value, err := read(6)
+					if err != nil {
+						return nil, 0, 0, err
+					}
+					if value >= 45 {
+						return nil, 0, 0, fmt.Errorf("invalid Alphanumeric value %d", value)
+					}
+					builder.WriteByte(alphanumChars[value])
+					count--
+				}
+			case Byte:
+				value, err := read(8)
+				if err != nil {
+					return nil, 0, 0, err
+				}
+				builder.WriteByte(byte(value))
+				count--
+			}
+		}
+		segments = append(segments, Segment{Mode: mode, Data: builder.String(), ECI: eci})
+		eci = 0
+	}
+
+	// Padding starts at the next codeword and alternates 0xEC and 0x11.
+	position = (position + 7) / 8 * 8
+	for i := 0; position < data.Len(); i++ {
+		pad, _ := read(8)
+		if pad != []int{0xec, 0x11}[i%2] {
+			return nil, 0, 0, fmt.Errorf("invalid pad codeword %02x", pad)
+		}
+	}
+
+	return segments, fnc1, indicator, nil
+}
+
+// Returns whether the module at (x, y) is inverted by the mask pattern.
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	case 7:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+	return false
+}
+
+// Returns the remainder of value shifted by n bits divided by the generator
+// polynomial of a BCH code.
+func bch(value, n, generator int) int {
+	remainder := value << n
+	for i := 30; i >= n; i-- {
+		if remainder&(1<<i) != 0 {
+			remainder ^= generator << (i - n)
+		}
+	}
+	return remainder
+}
+
+// Evaluates the codewords as a polynomial at the i-th power of the
+// generator of GF(256). The result is zero for valid Reed-Solomon codewords.
+func syndrome(codewords []byte, i int) byte {
+	root := byte(1)
+	for j := 0; j < i; j++ {
+		root = gfMultiply(root, 2)
+	}
+	var result byte
+	for _, c := range codewords {
+		result = gfMultiply(result, root) ^ c
+	}
+	return result
+}
+
+// Multiplies in GF(256) with the QR Code polynomial x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(a, b byte) byte {
+	var product byte
+	for b > 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+		carry := a&0x80 != 0
+		a <<= 1
+		if carry {
+			a ^= 0x1d
+		}
+		b >>= 1
+	}
+	return product
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}